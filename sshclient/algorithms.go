@@ -0,0 +1,156 @@
+package sshclient
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	algorithmProfileModern = "modern"
+	algorithmProfileCompat = "compat"
+)
+
+// Algorithms implemented by golang.org/x/crypto/ssh.
+var (
+	supportedCiphers = []string{
+		"chacha20-poly1305@openssh.com",
+		"aes128-gcm@openssh.com",
+		"aes256-ctr", "aes192-ctr", "aes128-ctr",
+		"aes128-cbc", "3des-cbc",
+		"arcfour256", "arcfour128", "arcfour",
+	}
+	supportedKeyExchanges = []string{
+		"curve25519-sha256", "curve25519-sha256@libssh.org",
+		"ecdh-sha2-nistp256", "ecdh-sha2-nistp384", "ecdh-sha2-nistp521",
+		"diffie-hellman-group-exchange-sha256",
+		"diffie-hellman-group14-sha256",
+		"diffie-hellman-group14-sha1",
+		"diffie-hellman-group-exchange-sha1",
+		"diffie-hellman-group1-sha1",
+	}
+	supportedMACs = []string{
+		"hmac-sha2-256-etm@openssh.com", "hmac-sha2-256",
+		"hmac-sha1", "hmac-sha1-96",
+	}
+	supportedHostKeyAlgorithms = []string{
+		ssh.CertAlgoED25519v01,
+		ssh.CertAlgoECDSA256v01, ssh.CertAlgoECDSA384v01, ssh.CertAlgoECDSA521v01,
+		ssh.CertAlgoRSASHA512v01, ssh.CertAlgoRSASHA256v01,
+		ssh.CertAlgoRSAv01, ssh.CertAlgoDSAv01,
+		ssh.KeyAlgoED25519,
+		ssh.KeyAlgoECDSA256, ssh.KeyAlgoECDSA384, ssh.KeyAlgoECDSA521,
+		ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256,
+		ssh.KeyAlgoRSA, ssh.KeyAlgoDSA,
+	}
+)
+
+// hostKeyAlgorithmKeyTypes maps host key algorithms to the types of the keys
+// they use where they differ, as RSA keys are signed with several algorithms.
+var hostKeyAlgorithmKeyTypes = map[string]string{
+	ssh.KeyAlgoRSASHA512:     ssh.KeyAlgoRSA,
+	ssh.KeyAlgoRSASHA256:     ssh.KeyAlgoRSA,
+	ssh.CertAlgoRSASHA512v01: ssh.CertAlgoRSAv01,
+	ssh.CertAlgoRSASHA256v01: ssh.CertAlgoRSAv01,
+}
+
+// hostKeyAlgorithmKeyType returns the type of the keys of host key algorithm.
+func hostKeyAlgorithmKeyType(algorithm string) string {
+	if t, ok := hostKeyAlgorithmKeyTypes[algorithm]; ok {
+		return t
+	}
+	return algorithm
+}
+
+// hostAlgorithms holds the algorithm lists of ssh.Config. A nil list means the
+// defaults of golang.org/x/crypto/ssh.
+type hostAlgorithms struct {
+	Ciphers           []string
+	KeyExchanges      []string
+	MACs              []string
+	HostKeyAlgorithms []string
+}
+
+var algorithmProfiles = map[string]hostAlgorithms{
+	// modern allows only algorithms without known weaknesses.
+	algorithmProfileModern: {
+		Ciphers: []string{
+			"chacha20-poly1305@openssh.com",
+			"aes128-gcm@openssh.com",
+			"aes256-ctr", "aes192-ctr", "aes128-ctr",
+		},
+		KeyExchanges: []string{
+			"curve25519-sha256", "curve25519-sha256@libssh.org",
+			"ecdh-sha2-nistp256", "ecdh-sha2-nistp384", "ecdh-sha2-nistp521",
+			"diffie-hellman-group-exchange-sha256",
+			"diffie-hellman-group14-sha256",
+		},
+		MACs: []string{
+			"hmac-sha2-256-etm@openssh.com", "hmac-sha2-256",
+		},
+		HostKeyAlgorithms: []string{
+			ssh.CertAlgoED25519v01,
+			ssh.CertAlgoECDSA256v01, ssh.CertAlgoECDSA384v01, ssh.CertAlgoECDSA521v01,
+			ssh.CertAlgoRSASHA512v01, ssh.CertAlgoRSASHA256v01,
+			ssh.KeyAlgoED25519,
+			ssh.KeyAlgoECDSA256, ssh.KeyAlgoECDSA384, ssh.KeyAlgoECDSA521,
+			ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256,
+		},
+	},
+	// compat allows every supported algorithm, preferring the modern ones, to
+	// connect to legacy devices.
+	algorithmProfileCompat: {
+		Ciphers:           supportedCiphers,
+		KeyExchanges:      supportedKeyExchanges,
+		MACs:              supportedMACs,
+		HostKeyAlgorithms: supportedHostKeyAlgorithms,
+	},
+}
+
+func validateAlgorithms(name string, algos, supported []string) error {
+	for _, a := range algos {
+		if !stringInSlice(a, supported) {
+			return fmt.Errorf("unsupported algorithm %q in %s, must be one of %s", a, name, strings.Join(supported, ", "))
+		}
+	}
+	return nil
+}
+
+func (h *host) validateAlgorithms() error {
+	if h.AlgorithmProfile != "" {
+		if _, ok := algorithmProfiles[h.AlgorithmProfile]; !ok {
+			return fmt.Errorf("unknown algorithm_profile %q, must be either %s or %s", h.AlgorithmProfile, algorithmProfileModern, algorithmProfileCompat)
+		}
+	}
+
+	if err := validateAlgorithms("ciphers", h.Ciphers, supportedCiphers); err != nil {
+		return err
+	}
+	if err := validateAlgorithms("key_exchanges", h.KeyExchanges, supportedKeyExchanges); err != nil {
+		return err
+	}
+	if err := validateAlgorithms("macs", h.MACs, supportedMACs); err != nil {
+		return err
+	}
+	return validateAlgorithms("host_key_algorithms", h.HostKeyAlgorithms, supportedHostKeyAlgorithms)
+}
+
+// algorithms returns the algorithm lists of algorithm_profile overridden by
+// the lists set explicitly.
+func (h *host) algorithms() hostAlgorithms {
+	a := algorithmProfiles[h.AlgorithmProfile]
+	if len(h.Ciphers) > 0 {
+		a.Ciphers = h.Ciphers
+	}
+	if len(h.KeyExchanges) > 0 {
+		a.KeyExchanges = h.KeyExchanges
+	}
+	if len(h.MACs) > 0 {
+		a.MACs = h.MACs
+	}
+	if len(h.HostKeyAlgorithms) > 0 {
+		a.HostKeyAlgorithms = h.HostKeyAlgorithms
+	}
+	return a
+}
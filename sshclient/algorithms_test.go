@@ -0,0 +1,73 @@
+package sshclient
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestHostValidateAlgorithms(t *testing.T) {
+	cases := []struct {
+		name    string
+		host    host
+		success bool
+	}{
+		{"default", host{}, true},
+		{"modern", host{AlgorithmProfile: "modern"}, true},
+		{"compat", host{AlgorithmProfile: "compat"}, true},
+		{"unknown profile", host{AlgorithmProfile: "legacy"}, false},
+		{"lists", host{Ciphers: []string{"aes256-ctr"}, KeyExchanges: []string{"curve25519-sha256@libssh.org"}, MACs: []string{"hmac-sha2-256"}, HostKeyAlgorithms: []string{"ssh-ed25519"}}, true},
+		{"RFC 8731 and 8268 kex", host{KeyExchanges: []string{"curve25519-sha256", "diffie-hellman-group14-sha256"}}, true},
+		{"unsupported cipher", host{Ciphers: []string{"aes256-gcm@openssh.com"}}, false},
+		{"unsupported kex", host{KeyExchanges: []string{"sntrup761x25519-sha512@openssh.com"}}, false},
+		{"unsupported mac", host{MACs: []string{"hmac-sha2-512"}}, false},
+		{"rsa-sha2 host key algorithms", host{HostKeyAlgorithms: []string{"rsa-sha2-512", "rsa-sha2-256"}}, true},
+		{"unsupported host key algorithm", host{HostKeyAlgorithms: []string{"ssh-ed448"}}, false},
+	}
+
+	for _, c := range cases {
+		err := c.host.validateAlgorithms()
+		if succeeded := err == nil; succeeded != c.success {
+			t.Errorf("Error status not match:\n\tCase: %s\n\tSucceeded?: %v\n\tExpected to succeed?: %v\n\tError: %v", c.name, succeeded, c.success, err)
+		}
+	}
+}
+
+func TestHostAlgorithms(t *testing.T) {
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	config.KeyExchanges = []string{"diffie-hellman-group1-sha1"}
+	config.Ciphers = []string{"aes128-cbc"}
+	server := newTestSSHServer(t, config)
+
+	cases := []struct {
+		name    string
+		modify  func(h *host)
+		success bool
+	}{
+		{"default", func(h *host) {}, false},
+		{"modern", func(h *host) { h.AlgorithmProfile = "modern" }, false},
+		{"compat", func(h *host) { h.AlgorithmProfile = "compat" }, true},
+		{"lists", func(h *host) {
+			h.KeyExchanges = []string{"diffie-hellman-group1-sha1"}
+			h.Ciphers = []string{"aes128-cbc"}
+		}, true},
+		{"profile overridden", func(h *host) {
+			h.AlgorithmProfile = "compat"
+			h.Ciphers = []string{"aes128-ctr"}
+		}, false},
+	}
+
+	for _, c := range cases {
+		h := server.host("user")
+		h.Password = "password"
+		c.modify(h)
+
+		client, err := h.Dial()
+		if err == nil {
+			client.Close()
+		}
+		if succeeded := err == nil; succeeded != c.success {
+			t.Errorf("Error status not match:\n\tCase: %s\n\tSucceeded?: %v\n\tExpected to succeed?: %v\n\tError: %v", c.name, succeeded, c.success, err)
+		}
+	}
+}
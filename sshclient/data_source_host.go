@@ -1,15 +1,25 @@
 package sshclient
 
 import (
+	"bytes"
 	"context"
-	"encoding/json"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"io"
+	"net"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
 )
 
 func dataSourceHost() *schema.Resource {
@@ -33,32 +43,254 @@ func dataSourceHost() *schema.Resource {
 			"port": {
 				Type:        schema.TypeInt,
 				Optional:    true,
-				Description: "If no port specified, 22 is used as default port.",
+				Description: "If no port specified, the port of the provider or 22 is used as default port.",
 			},
 			"username": {
 				Type:     schema.TypeString,
 				Optional: true,
 			},
+			"connect_timeout": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Timeout to establish TCP connections, such as 30s. If not specified, there is no timeout.",
+			},
 			"client_private_key_pem": {
 				Type:        schema.TypeString,
 				Optional:    true,
 				Description: "Client private key in PEM format.",
 				Sensitive:   true,
 			},
+			"client_private_key_passphrase": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Passphrase to decrypt client_private_key_pem.",
+				Sensitive:   true,
+			},
+			"client_certificate": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "OpenSSH user certificate for client_private_key_pem in authorized_keys (sshd(8)) format.",
+			},
+			"password_from":                      secretSourceSchema("password"),
+			"client_private_key_pem_from":        secretSourceSchema("client_private_key_pem"),
+			"client_private_key_passphrase_from": secretSourceSchema("client_private_key_passphrase"),
+			"keyboard_interactive": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Description: "Authenticate with keyboard-interactive instead of password. Prompts not matched by keyboard_interactive_answers are answered with password.",
+			},
+			"keyboard_interactive_answers": {
+				Type:        schema.TypeMap,
+				Optional:    true,
+				Sensitive:   true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "Map from regular expressions matched against keyboard-interactive prompts to their answers. If multiple expressions match, the lexicographically smallest one is used.",
+			},
+			"keyboard_interactive_answers_from": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Description: "Sources of answers to keyboard-interactive prompts read when connecting, so that they are not stored in host_json nor in the state. They are used like keyboard_interactive_answers, which cannot have the same patterns. Exactly one of env, file and command is needed in each source.",
+				Elem: &schema.Resource{
+					Schema: keyboardInteractiveAnswerSourceAttributes(),
+				},
+			},
+			"auth_methods": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "Auth methods tried in order, out of password, keyboard_interactive, private_key and agent. All of them may be needed if the server requires multiple methods, like AuthenticationMethods of sshd_config(5). private_key and agent are offered together in the position of the first of them. If not specified, exactly one of password (or keyboard_interactive), client_private_key_pem and use_agent is used.",
+			},
+			"use_agent": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Description: "Authenticate with every identity offered by the ssh-agent.",
+			},
+			"agent_socket": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Path to the ssh-agent socket. If not specified, SSH_AUTH_SOCK is used.",
+			},
 			"host_publickey_authorized_key": {
 				Type:        schema.TypeString,
 				Optional:    true,
 				Description: "Host public key trusted in authorized_keys (sshd(8)) format.",
 			},
+			"host_publickey_authorized_keys": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "Host public keys trusted in authorized_keys (sshd(8)) format. Any of them is accepted, so that host keys can be rotated.",
+			},
+			"host_ca_authorized_keys": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Certificate authority public keys in authorized_keys (sshd(8)) format, one per line. Host certificates signed by any of them are trusted.",
+			},
+			"host_certificate_principals": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "Principals accepted in host certificates. If not specified, hostname is used.",
+			},
+			"host_revoked_keys": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Revoked host keys, certificate authorities and host certificates in authorized_keys (sshd(8)) format, one per line.",
+			},
+			"known_hosts": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Trusted host keys in known_hosts (sshd(8)) format. Hashed hostnames and @cert-authority and @revoked markers are supported.",
+			},
+			"known_hosts_file": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Path to a file in known_hosts (sshd(8)) format, read when connecting.",
+			},
 			"insecure_ignore_host_key": {
 				Type:        schema.TypeBool,
 				Default:     false,
 				Optional:    true,
 				Description: "Insecurely trust the host public key. This may potentially cause Man-In-The-Middle attack.",
 			},
+			"proxy": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				MaxItems:    1,
+				Description: "Proxy to connect through. This cannot be used with jump_host_json.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"type": {
+							Type:        schema.TypeString,
+							Required:    true,
+							Description: "Either socks5 or http. http uses the CONNECT method.",
+						},
+						"hostname": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"port": {
+							Type:     schema.TypeInt,
+							Required: true,
+						},
+						"username": {
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+						"password": {
+							Type:      schema.TypeString,
+							Optional:  true,
+							Sensitive: true,
+						},
+						"password_from": secretSourceSchema("password"),
+					},
+				},
+			},
+			"proxy_command": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Command whose stdin and stdout are used as the connection, like ProxyCommand of ssh_config(5). %h, %p and %r are replaced by hostname, port and username, and %% by %. This cannot be used with proxy or jump_host_json.",
+			},
+			"address_family": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Address family used when connecting, either any, inet (IPv4 only) or inet6 (IPv6 only), like AddressFamily of ssh_config(5). Defaults to any.",
+			},
+			"bind_address": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Local IP address to connect from, like BindAddress of ssh_config(5).",
+			},
+			"algorithm_profile": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Named set of algorithms, either modern, allowing only algorithms without known weaknesses, or compat, allowing every supported algorithm for legacy devices. The lists set explicitly override the profile. If not specified, the defaults of golang.org/x/crypto/ssh are used.",
+			},
+			"ciphers": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "Allowed ciphers in order of preference, like Ciphers of ssh_config(5).",
+			},
+			"key_exchanges": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "Allowed key exchange algorithms in order of preference, like KexAlgorithms of ssh_config(5).",
+			},
+			"macs": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "Allowed MAC algorithms in order of preference, like MACs of ssh_config(5).",
+			},
+			"host_key_algorithms": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "Allowed host key algorithms in order of preference, like HostKeyAlgorithms of ssh_config(5). If not set, the types of the pinned keys and the keys in known_hosts for the host are preferred like OpenSSH does.",
+			},
+			"keepalive_interval": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Interval to send keepalive@openssh.com requests while running, like ServerAliveInterval of ssh_config(5). If not specified, no keepalives are sent.",
+			},
+			"keepalive_count_max": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Description: "Number of keepalives in a row without response after which the connection is considered lost, like ServerAliveCountMax of ssh_config(5). Defaults to 3.",
+			},
+			"retry": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				MaxItems:    1,
+				Description: "Retry policy for connections, such as to hosts still booting.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"max_attempts": {
+							Type:        schema.TypeInt,
+							Required:    true,
+							Description: "Maximum number of connection attempts including the first one.",
+						},
+						"initial_backoff": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "Wait before the second attempt, doubled for each following attempt. Defaults to 1s.",
+						},
+						"max_backoff": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "Maximum wait between attempts. Defaults to 30s.",
+						},
+						"jitter": {
+							Type:        schema.TypeFloat,
+							Optional:    true,
+							Description: "Fraction between 0 and 1 by which each wait is randomly shortened.",
+						},
+						"timeout": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "Total duration after which no more attempts are made. If not specified, only max_attempts limits retries.",
+						},
+						"retryable_errors": {
+							Type:        schema.TypeList,
+							Optional:    true,
+							Elem:        &schema.Schema{Type: schema.TypeString},
+							Description: "Kinds of errors retried, out of connection_refused, timeout, unreachable, reset, host_key and auth. Defaults to connection_refused, timeout, unreachable and reset.",
+						},
+					},
+				},
+			},
+			"jump_host_json": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Sensitive:   true,
+				Description: "JSON of the host to jump through, like ProxyJump of ssh(1). The jump host can have its own jump host.",
+			},
 			"json": {
-				Type:     schema.TypeString,
-				Computed: true,
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Versioned JSON of the host used as host_json. Unknown fields are rejected, and JSON of older versions is migrated to the latest version.",
 			},
 		},
 	}
@@ -69,23 +301,181 @@ const (
 	tcpPortMax int = 65535
 )
 
+const (
+	authMethodPassword            = "password"
+	authMethodKeyboardInteractive = "keyboard_interactive"
+	authMethodPrivateKey          = "private_key"
+	authMethodAgent               = "agent"
+)
+
+var authMethods = []string{
+	authMethodPassword,
+	authMethodKeyboardInteractive,
+	authMethodPrivateKey,
+	authMethodAgent,
+}
+
+const (
+	addressFamilyAny   = "any"
+	addressFamilyInet  = "inet"
+	addressFamilyInet6 = "inet6"
+)
+
 type host struct {
-	Hostname                   string `json:"hostname"`
-	Port                       int    `json:"port"`
-	Username                   string `json:"username"`
-	Password                   string `json:"password"`
-	ClientPrivateKeyPem        string `json:"client_private_key_pem"`
-	HostPublickeyAuthorizedKey string `json:"host_publickey_authorized_key"`
-	InsecureIgnoreHostKey      bool   `json:"insecure_ignore_host_key"`
+	Version                        int                      `json:"version"`
+	Hostname                       string                   `json:"hostname"`
+	Port                           int                      `json:"port"`
+	Username                       string                   `json:"username"`
+	ConnectTimeout                 string                   `json:"connect_timeout"`
+	Password                       string                   `json:"password"`
+	ClientPrivateKeyPem            string                   `json:"client_private_key_pem"`
+	ClientPrivateKeyPassphrase     string                   `json:"client_private_key_passphrase"`
+	PasswordFrom                   *secretSource            `json:"password_from"`
+	ClientPrivateKeyPemFrom        *secretSource            `json:"client_private_key_pem_from"`
+	ClientPrivateKeyPassphraseFrom *secretSource            `json:"client_private_key_passphrase_from"`
+	ClientCertificate              string                   `json:"client_certificate"`
+	KeyboardInteractive            bool                     `json:"keyboard_interactive"`
+	KeyboardInteractiveAnswers     map[string]string        `json:"keyboard_interactive_answers"`
+	KeyboardInteractiveAnswersFrom map[string]*secretSource `json:"keyboard_interactive_answers_from"`
+	UseAgent                       bool                     `json:"use_agent"`
+	AgentSocket                    string                   `json:"agent_socket"`
+	AuthMethods                    []string                 `json:"auth_methods"`
+	HostPublickeyAuthorizedKey     string                   `json:"host_publickey_authorized_key"`
+	HostPublickeyAuthorizedKeys    []string                 `json:"host_publickey_authorized_keys"`
+	HostCaAuthorizedKeys           string                   `json:"host_ca_authorized_keys"`
+	HostCertificatePrincipals      []string                 `json:"host_certificate_principals"`
+	HostRevokedKeys                string                   `json:"host_revoked_keys"`
+	KnownHosts                     string                   `json:"known_hosts"`
+	KnownHostsFile                 string                   `json:"known_hosts_file"`
+	InsecureIgnoreHostKey          bool                     `json:"insecure_ignore_host_key"`
+	JumpHost                       *host                    `json:"jump_host"`
+	Proxy                          *hostProxy               `json:"proxy"`
+	ProxyCommand                   string                   `json:"proxy_command"`
+	Retry                          *hostRetry               `json:"retry"`
+	KeepaliveInterval              string                   `json:"keepalive_interval"`
+	KeepaliveCountMax              int                      `json:"keepalive_count_max"`
+	AddressFamily                  string                   `json:"address_family"`
+	BindAddress                    string                   `json:"bind_address"`
+	AlgorithmProfile               string                   `json:"algorithm_profile"`
+	Ciphers                        []string                 `json:"ciphers"`
+	KeyExchanges                   []string                 `json:"key_exchanges"`
+	MACs                           []string                 `json:"macs"`
+	HostKeyAlgorithms              []string                 `json:"host_key_algorithms"`
+
+	// matchedHostKey is the host key accepted on the last handshake.
+	matchedHostKey ssh.PublicKey
+	// authenticatedMethod is the auth method completing the last
+	// authentication.
+	authenticatedMethod string
+	// attemptedMethod is the auth method tried last in the ongoing
+	// handshake, which becomes authenticatedMethod if the handshake succeeds.
+	attemptedMethod string
+	// resolvedSecrets holds the secret fields resolved from their *_from
+	// sources by field name.
+	resolvedSecrets map[string]string
+	// pool shares connections if not nil.
+	pool *connPool
 }
 
 func (h *host) String() string {
-	hostPart := fmt.Sprintf("%s@%s:%d", h.Username, h.Hostname, h.Port)
-	auth := h.stringAuthMethod()
-	if auth == "" {
-		return hostPart
+	hostPart := fmt.Sprintf("%s@%s", h.Username, h.addr())
+	if auth := h.stringAuthMethod(); auth != "" {
+		hostPart = fmt.Sprintf("%s (Auth with %s)", hostPart, auth)
+	}
+	if h.JumpHost != nil {
+		hostPart = fmt.Sprintf("%s via %s", hostPart, h.JumpHost)
+	}
+	if h.Proxy != nil {
+		hostPart = fmt.Sprintf("%s via %s", hostPart, h.Proxy)
+	}
+	if h.ProxyCommand != "" {
+		hostPart = fmt.Sprintf("%s via proxy_command", hostPart)
+	}
+	return hostPart
+}
+
+// hasAuthInfo reports whether any authentication is configured.
+func (h *host) hasAuthInfo() bool {
+	return h.hasPassword() || h.KeyboardInteractive || h.hasPrivateKey() || h.UseAgent
+}
+
+// hasHostKeyInfo reports whether any host key verification, including
+// insecure_ignore_host_key, is configured.
+func (h *host) hasHostKeyInfo() bool {
+	return h.HostPublickeyAuthorizedKey != "" ||
+		len(h.HostPublickeyAuthorizedKeys) > 0 ||
+		h.HostCaAuthorizedKeys != "" ||
+		h.KnownHosts != "" ||
+		h.KnownHostsFile != "" ||
+		h.InsecureIgnoreHostKey
+}
+
+func (h *host) connectTimeout() (time.Duration, error) {
+	if h.ConnectTimeout == "" {
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(h.ConnectTimeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid connect_timeout: %w", err)
+	}
+	return d, nil
+}
+
+// joinHostPort returns the address of hostname and port, enclosing IPv6
+// literals in brackets.
+func joinHostPort(hostname string, port int) string {
+	return net.JoinHostPort(hostname, strconv.Itoa(port))
+}
+
+func (h *host) addr() string {
+	return joinHostPort(h.Hostname, h.Port)
+}
+
+// network returns the network for net.Dial according to address_family.
+func (h *host) network() (string, error) {
+	switch h.AddressFamily {
+	case "", addressFamilyAny:
+		return "tcp", nil
+	case addressFamilyInet:
+		return "tcp4", nil
+	case addressFamilyInet6:
+		return "tcp6", nil
+	}
+	return "", fmt.Errorf("unknown address_family %q, must be one of %s, %s or %s", h.AddressFamily, addressFamilyAny, addressFamilyInet, addressFamilyInet6)
+}
+
+func (h *host) dialer() (*net.Dialer, error) {
+	timeout, err := h.connectTimeout()
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{Timeout: timeout}
+
+	if h.BindAddress != "" {
+		ip := net.ParseIP(h.BindAddress)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid bind_address %q: not an IP address", h.BindAddress)
+		}
+		if (h.AddressFamily == addressFamilyInet && ip.To4() == nil) || (h.AddressFamily == addressFamilyInet6 && ip.To4() != nil) {
+			return nil, fmt.Errorf("bind_address %s does not match address_family %s", h.BindAddress, h.AddressFamily)
+		}
+		dialer.LocalAddr = &net.TCPAddr{IP: ip}
 	}
-	return fmt.Sprintf("%s (Auth with %s)", hostPart, auth)
+
+	return dialer, nil
+}
+
+// validate checks h before connecting. Authentication is checked only if auth
+// is true because keyscan connects without authenticating.
+func (h *host) validate(auth bool) error {
+	if err := h.validateHostInfo(); err != nil {
+		return err
+	}
+	if auth {
+		return h.validateAuthInfo()
+	}
+	return nil
 }
 
 func (h *host) validateHostInfo() error {
@@ -101,118 +491,629 @@ func (h *host) validateHostInfo() error {
 		return fmt.Errorf("username is not provided")
 	}
 
-	if (h.HostPublickeyAuthorizedKey == "") == !h.InsecureIgnoreHostKey {
-		return fmt.Errorf("exactly one of host_publickey_authorized_key and insecure_ignore_host_key is needed")
+	if _, err := h.connectTimeout(); err != nil {
+		return err
+	}
+
+	verified := h.HostPublickeyAuthorizedKey != "" ||
+		len(h.HostPublickeyAuthorizedKeys) > 0 ||
+		h.HostCaAuthorizedKeys != "" ||
+		h.KnownHosts != "" ||
+		h.KnownHostsFile != ""
+	if verified == h.InsecureIgnoreHostKey {
+		return fmt.Errorf("exactly one of host key verification (host_publickey_authorized_key, host_publickey_authorized_keys, host_ca_authorized_keys, known_hosts or known_hosts_file) and insecure_ignore_host_key is needed")
+	}
+
+	if _, err := h.network(); err != nil {
+		return err
+	}
+	if _, err := h.dialer(); err != nil {
+		return err
+	}
+
+	if err := h.validateAlgorithms(); err != nil {
+		return err
+	}
+
+	if _, err := h.keepaliveInterval(); err != nil {
+		return err
+	}
+	if h.KeepaliveCountMax < 0 {
+		return fmt.Errorf("keepalive_count_max must not be negative")
+	}
+
+	if h.Retry != nil {
+		if err := h.Retry.validate(); err != nil {
+			return err
+		}
+	}
+
+	if h.ProxyCommand != "" {
+		if h.Proxy != nil || h.JumpHost != nil {
+			return fmt.Errorf("proxy_command cannot be used with proxy or jump host")
+		}
+		if _, err := h.expandProxyCommand(h.ProxyCommand); err != nil {
+			return err
+		}
+	}
+
+	if h.Proxy != nil {
+		if h.JumpHost != nil {
+			return fmt.Errorf("proxy cannot be used with jump host")
+		}
+		if err := h.Proxy.validate(); err != nil {
+			return err
+		}
+		if err := validateSecretSources(h.proxySecretFields()); err != nil {
+			return err
+		}
+	}
+
+	if h.JumpHost != nil {
+		if err := h.JumpHost.validate(true); err != nil {
+			return fmt.Errorf("jump host: %w", err)
+		}
 	}
 
 	return nil
 }
 
 func (h *host) validateAuthInfo() error {
-	if (h.Password == "") == (h.ClientPrivateKeyPem == "") {
-		return fmt.Errorf("exactly one of password and client_private_key_pem is needed")
+	if len(h.AuthMethods) == 0 {
+		n := 0
+		for _, set := range []bool{
+			h.hasPassword() || h.KeyboardInteractive,
+			h.hasPrivateKey(),
+			h.UseAgent,
+		} {
+			if set {
+				n++
+			}
+		}
+		if n != 1 {
+			return fmt.Errorf("exactly one of password (or keyboard_interactive), client_private_key_pem and use_agent is needed unless auth_methods is set")
+		}
+	} else if err := h.validateAuthMethods(); err != nil {
+		return err
+	}
+
+	if err := validateSecretSources(h.authSecretFields()); err != nil {
+		return err
+	}
+
+	if h.ClientCertificate != "" && !h.hasPrivateKey() {
+		return fmt.Errorf("client_certificate needs client_private_key_pem")
+	}
+
+	if stringInSlice(authMethodKeyboardInteractive, h.authMethodNames()) {
+		if !h.hasPassword() && len(h.KeyboardInteractiveAnswers) == 0 && len(h.KeyboardInteractiveAnswersFrom) == 0 {
+			return fmt.Errorf("keyboard_interactive needs password or keyboard_interactive_answers")
+		}
+		if _, err := h.keyboardInteractiveAnswerers(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateAuthMethods checks that each method in auth_methods is known, listed
+// once, and has its credentials.
+func (h *host) validateAuthMethods() error {
+	if h.KeyboardInteractive {
+		return fmt.Errorf("keyboard_interactive cannot be used with auth_methods, list keyboard_interactive in auth_methods instead")
+	}
+
+	for i, m := range h.AuthMethods {
+		if !stringInSlice(m, authMethods) {
+			return fmt.Errorf("unknown auth method %q in auth_methods, must be one of %s", m, strings.Join(authMethods, ", "))
+		}
+		if stringInSlice(m, h.AuthMethods[:i]) {
+			return fmt.Errorf("auth method %s is listed more than once in auth_methods", m)
+		}
+
+		switch m {
+		case authMethodPassword:
+			if !h.hasPassword() {
+				return fmt.Errorf("auth method password needs password")
+			}
+		case authMethodPrivateKey:
+			if !h.hasPrivateKey() {
+				return fmt.Errorf("auth method private_key needs client_private_key_pem")
+			}
+		}
 	}
 
 	return nil
 }
 
 func (h *host) stringAuthMethod() string {
-	if h.Password != "" {
-		return "password"
-	} else if h.ClientPrivateKeyPem != "" {
-		return "private key"
+	var names []string
+	for _, m := range h.authMethodNames() {
+		switch m {
+		case authMethodKeyboardInteractive:
+			names = append(names, "keyboard-interactive")
+		case authMethodPassword:
+			names = append(names, "password")
+		case authMethodPrivateKey:
+			if h.ClientCertificate != "" {
+				names = append(names, "certificate")
+			} else {
+				names = append(names, "private key")
+			}
+		case authMethodAgent:
+			names = append(names, "agent")
+		}
 	}
-	return ""
+	return strings.Join(names, ", ")
 }
 
-func (h *host) authMethod() ([]ssh.AuthMethod, error) {
-	var auth []ssh.AuthMethod
-	if h.Password != "" {
-		auth = append(auth, ssh.Password(h.Password))
-	} else if h.ClientPrivateKeyPem != "" {
-		key, err := ssh.ParsePrivateKey([]byte(h.ClientPrivateKeyPem))
-		if err != nil {
-			return nil, err
+// parsePrivateKey parses the client private key, decrypting it with the
+// passphrase if the key is encrypted.
+func (h *host) parsePrivateKey() (ssh.Signer, error) {
+	key, err := ssh.ParsePrivateKey([]byte(h.clientPrivateKeyPem()))
+	if err == nil {
+		return key, nil
+	}
+
+	var missing *ssh.PassphraseMissingError
+	if !errors.As(err, &missing) {
+		return nil, fmt.Errorf("unsupported private key format: %w", err)
+	}
+
+	if h.clientPrivateKeyPassphrase() == "" {
+		return nil, fmt.Errorf("private key is passphrase protected but client_private_key_passphrase is not provided")
+	}
+
+	key, err = ssh.ParsePrivateKeyWithPassphrase([]byte(h.clientPrivateKeyPem()), []byte(h.clientPrivateKeyPassphrase()))
+	if errors.Is(err, x509.IncorrectPasswordError) {
+		return nil, fmt.Errorf("wrong passphrase for private key")
+	} else if err != nil {
+		return nil, fmt.Errorf("unsupported encrypted private key format: %w", err)
+	}
+
+	return key, nil
+}
+
+// certSigner combines key with the user certificate given in authorized_keys
+// format, checking that the certificate is for key and valid at now.
+func certSigner(key ssh.Signer, authorizedCert string, now time.Time) (ssh.Signer, error) {
+	pub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(authorizedCert))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse client_certificate: %w", err)
+	}
+
+	cert, ok := pub.(*ssh.Certificate)
+	if !ok {
+		return nil, fmt.Errorf("client_certificate is not a certificate but a %s key", pub.Type())
+	}
+
+	if cert.CertType != ssh.UserCert {
+		return nil, fmt.Errorf("client_certificate is not a user certificate")
+	}
+
+	if !bytes.Equal(cert.Key.Marshal(), key.PublicKey().Marshal()) {
+		return nil, fmt.Errorf("client_certificate does not match client_private_key_pem")
+	}
+
+	unix := uint64(now.Unix())
+	if unix < cert.ValidAfter {
+		return nil, fmt.Errorf("client_certificate is not yet valid: valid after %s", time.Unix(int64(cert.ValidAfter), 0).UTC())
+	}
+	if cert.ValidBefore != ssh.CertTimeInfinity && unix >= cert.ValidBefore {
+		return nil, fmt.Errorf("client_certificate has expired: valid before %s", time.Unix(int64(cert.ValidBefore), 0).UTC())
+	}
+
+	return ssh.NewCertSigner(cert, key)
+}
+
+type keyboardInteractiveAnswerer struct {
+	pattern *regexp.Regexp
+	answer  string
+}
+
+// keyboardInteractiveAnswerers compiles keyboard_interactive_answers in the
+// order they are tried.
+func (h *host) keyboardInteractiveAnswerers() ([]keyboardInteractiveAnswerer, error) {
+	patterns := make([]string, 0, len(h.KeyboardInteractiveAnswers)+len(h.KeyboardInteractiveAnswersFrom))
+	for p := range h.KeyboardInteractiveAnswers {
+		patterns = append(patterns, p)
+	}
+	for p := range h.KeyboardInteractiveAnswersFrom {
+		if _, ok := h.KeyboardInteractiveAnswers[p]; !ok {
+			patterns = append(patterns, p)
 		}
+	}
+	sort.Strings(patterns)
 
-		auth = append(auth, ssh.PublicKeys(key))
+	answerers := make([]keyboardInteractiveAnswerer, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid keyboard_interactive_answers pattern %q: %w", p, err)
+		}
+		answerers = append(answerers, keyboardInteractiveAnswerer{
+			pattern: re,
+			answer:  h.keyboardInteractiveAnswer(p),
+		})
 	}
 
-	return auth, nil
+	return answerers, nil
 }
 
-func (h *host) ClientConfig() (*ssh.ClientConfig, error) {
-	auth, err := h.authMethod()
+func (h *host) keyboardInteractiveChallenge() (ssh.KeyboardInteractiveChallenge, error) {
+	answerers, err := h.keyboardInteractiveAnswerers()
 	if err != nil {
 		return nil, err
 	}
 
-	var cb ssh.HostKeyCallback
-	if h.InsecureIgnoreHostKey {
-		cb = ssh.InsecureIgnoreHostKey()
-	} else {
-		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(h.HostPublickeyAuthorizedKey))
-		cb = ssh.FixedHostKey(key)
+	return func(user, instruction string, questions []string, echos []bool) ([]string, error) {
+		answers := make([]string, len(questions))
+		for i, q := range questions {
+			answer, ok := h.password(), h.password() != ""
+			for _, a := range answerers {
+				if a.pattern.MatchString(q) {
+					answer, ok = a.answer, true
+					break
+				}
+			}
+			if !ok {
+				return nil, fmt.Errorf("no answer for keyboard-interactive prompt %q", q)
+			}
+			answers[i] = answer
+		}
+		return answers, nil
+	}, nil
+}
+
+func (h *host) agentSocket() string {
+	if h.AgentSocket != "" {
+		return h.AgentSocket
+	}
+	return os.Getenv("SSH_AUTH_SOCK")
+}
+
+// authMethodNames returns auth_methods, or the single method inferred from the
+// credentials if auth_methods is not set.
+func (h *host) authMethodNames() []string {
+	if len(h.AuthMethods) > 0 {
+		return h.AuthMethods
+	}
+	if h.KeyboardInteractive {
+		return []string{authMethodKeyboardInteractive}
+	} else if h.hasPassword() {
+		return []string{authMethodPassword}
+	} else if h.hasPrivateKey() {
+		return []string{authMethodPrivateKey}
+	} else if h.UseAgent {
+		return []string{authMethodAgent}
+	}
+	return nil
+}
+
+// recordingSigner records its auth method as attempted when signing, because
+// only keys accepted by the server are used to sign.
+type recordingSigner struct {
+	ssh.Signer
+	method string
+	h      *host
+}
+
+func (s *recordingSigner) Sign(rand io.Reader, data []byte) (*ssh.Signature, error) {
+	s.h.attemptedMethod = s.method
+	return s.Signer.Sign(rand, data)
+}
+
+// recordingAlgorithmSigner is recordingSigner for ssh.AlgorithmSigner, which
+// is needed to sign with rsa-sha2-256 and rsa-sha2-512.
+type recordingAlgorithmSigner struct {
+	*recordingSigner
+	algorithmSigner ssh.AlgorithmSigner
+}
+
+func (s *recordingAlgorithmSigner) SignWithAlgorithm(rand io.Reader, data []byte, algorithm string) (*ssh.Signature, error) {
+	s.h.attemptedMethod = s.method
+	return s.algorithmSigner.SignWithAlgorithm(rand, data, algorithm)
+}
+
+// newRecordingSigner wraps signer to record method, keeping
+// ssh.AlgorithmSigner if signer implements it.
+func (h *host) newRecordingSigner(signer ssh.Signer, method string) ssh.Signer {
+	s := &recordingSigner{Signer: signer, method: method, h: h}
+	if as, ok := signer.(ssh.AlgorithmSigner); ok {
+		return &recordingAlgorithmSigner{recordingSigner: s, algorithmSigner: as}
+	}
+	return s
+}
+
+// authMethod builds the auth methods for h. The returned function releases
+// resources held by the methods, such as the ssh-agent connection, and must be
+// called once the handshake is done.
+func (h *host) authMethod() ([]ssh.AuthMethod, func(), error) {
+	var auth []ssh.AuthMethod
+	var closers []io.Closer
+	cleanup := func() {
+		for _, c := range closers {
+			c.Close()
+		}
+	}
+
+	// private_key and agent are both the publickey method, which
+	// golang.org/x/crypto/ssh tries only once, so their keys are offered
+	// together in the position of the first of them.
+	var keySources []func() ([]ssh.Signer, error)
+	publicKeys := -1
+
+	for _, name := range h.authMethodNames() {
+		switch name {
+		case authMethodPassword:
+			auth = append(auth, ssh.PasswordCallback(func() (string, error) {
+				h.attemptedMethod = authMethodPassword
+				return h.password(), nil
+			}))
+		case authMethodKeyboardInteractive:
+			challenge, err := h.keyboardInteractiveChallenge()
+			if err != nil {
+				cleanup()
+				return nil, nil, err
+			}
+
+			auth = append(auth, ssh.KeyboardInteractive(func(user, instruction string, questions []string, echos []bool) ([]string, error) {
+				h.attemptedMethod = authMethodKeyboardInteractive
+				return challenge(user, instruction, questions, echos)
+			}))
+		case authMethodPrivateKey:
+			key, err := h.parsePrivateKey()
+			if err != nil {
+				cleanup()
+				return nil, nil, err
+			}
+
+			if h.ClientCertificate != "" {
+				key, err = certSigner(key, h.ClientCertificate, time.Now())
+				if err != nil {
+					cleanup()
+					return nil, nil, err
+				}
+			}
+
+			signer := h.newRecordingSigner(key, authMethodPrivateKey)
+			keySources = append(keySources, func() ([]ssh.Signer, error) {
+				return []ssh.Signer{signer}, nil
+			})
+		case authMethodAgent:
+			sock := h.agentSocket()
+			if sock == "" {
+				cleanup()
+				return nil, nil, fmt.Errorf("use_agent is set but neither agent_socket nor SSH_AUTH_SOCK is provided")
+			}
+
+			conn, err := net.Dial("unix", sock)
+			if err != nil {
+				cleanup()
+				return nil, nil, fmt.Errorf("failed to connect to ssh-agent: %w", err)
+			}
+			closers = append(closers, conn)
+
+			client := agent.NewClient(conn)
+			keySources = append(keySources, func() ([]ssh.Signer, error) {
+				agentSigners, err := client.Signers()
+				if err != nil {
+					return nil, fmt.Errorf("failed to get keys from ssh-agent: %w", err)
+				}
+				var signers []ssh.Signer
+				for _, s := range agentSigners {
+					signers = append(signers, h.newRecordingSigner(s, authMethodAgent))
+				}
+				return signers, nil
+			})
+		}
+
+		if publicKeys < 0 && (name == authMethodPrivateKey || name == authMethodAgent) {
+			publicKeys = len(auth)
+			auth = append(auth, nil)
+		}
+	}
+
+	if publicKeys >= 0 {
+		auth[publicKeys] = ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+			var signers []ssh.Signer
+			for _, source := range keySources {
+				s, err := source()
+				if err != nil {
+					return nil, err
+				}
+				signers = append(signers, s...)
+			}
+			return signers, nil
+		})
+	}
+
+	return auth, cleanup, nil
+}
+
+// ClientConfig builds the client config for h. The returned function must be
+// called once the handshake is done.
+func (h *host) ClientConfig() (*ssh.ClientConfig, func(), error) {
+	if err := h.resolveSecrets(h.secretFields()); err != nil {
+		return nil, nil, err
+	}
+
+	cb, err := h.hostKeyCallback()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	algos := h.algorithms()
+	if len(h.HostKeyAlgorithms) == 0 {
+		types, err := h.hostKeyTypes()
 		if err != nil {
-			return nil, err
+			return nil, nil, err
+		}
+		if len(types) > 0 {
+			if algos.HostKeyAlgorithms == nil {
+				algos.HostKeyAlgorithms = supportedHostKeyAlgorithms
+			}
+			algos.HostKeyAlgorithms = preferHostKeyAlgorithms(algos.HostKeyAlgorithms, types)
 		}
 	}
 
+	auth, cleanup, err := h.authMethod()
+	if err != nil {
+		return nil, nil, err
+	}
+
 	return &ssh.ClientConfig{
-		User:            h.Username,
-		Auth:            auth,
-		HostKeyCallback: cb,
-	}, nil
+		Config: ssh.Config{
+			Ciphers:      algos.Ciphers,
+			KeyExchanges: algos.KeyExchanges,
+			MACs:         algos.MACs,
+		},
+		User:              h.Username,
+		Auth:              auth,
+		HostKeyCallback:   h.recordHostKey(cb),
+		HostKeyAlgorithms: algos.HostKeyAlgorithms,
+	}, cleanup, nil
 }
 
-func (h *host) RunCommand(command string, stdout io.Writer, stderr io.Writer) error {
-	config, err := h.ClientConfig()
+// MatchedHostKey returns the host key accepted on the last handshake in
+// authorized_keys (sshd(8)) format, or an empty string if there is none.
+func (h *host) MatchedHostKey() string {
+	if h.matchedHostKey == nil {
+		return ""
+	}
+	return string(ssh.MarshalAuthorizedKey(h.matchedHostKey))
+}
+
+// AuthenticatedMethod returns the auth method that completed the last
+// authentication, such as password or private_key.
+func (h *host) AuthenticatedMethod() string {
+	return h.authenticatedMethod
+}
+
+// Dial connects and authenticates to h, tunneling through the jump hosts if
+// any.
+func (h *host) Dial() (*ssh.Client, error) {
+	config, cleanup, err := h.ClientConfig()
 	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	return h.dial(config)
+}
+
+func (h *host) dial(config *ssh.ClientConfig) (*ssh.Client, error) {
+	var client *ssh.Client
+	err := h.withRetry(func() error {
+		var err error
+		client, err = h.dialOnce(config)
 		return err
+	})
+	return client, err
+}
+
+func (h *host) dialOnce(config *ssh.ClientConfig) (*ssh.Client, error) {
+	addr := h.addr()
+	conn, err := h.dialConn(addr)
+	if err != nil {
+		return nil, err
 	}
 
-	conn, err := ssh.Dial("tcp", fmt.Sprintf("%s:%d", h.Hostname, h.Port), config)
+	h.attemptedMethod = ""
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
 	if err != nil {
-		return err
+		conn.Close()
+		return nil, err
 	}
-	defer conn.Close()
 
-	session, err := conn.NewSession()
+	// Methods tried before may have failed, so the last one succeeded.
+	h.authenticatedMethod = h.attemptedMethod
+	return ssh.NewClient(c, chans, reqs), nil
+}
+
+// dialConn opens the transport to addr through the proxy command, the jump
+// host or the proxy if any.
+func (h *host) dialConn(addr string) (net.Conn, error) {
+	if h.ProxyCommand != "" {
+		return h.dialProxyCommand()
+	}
+
+	network, err := h.network()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer session.Close()
 
-	session.Stdout = stdout
-	session.Stderr = stderr
-	if err := session.Run(command); err != nil {
-		return err
+	dialer, err := h.dialer()
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
-}
+	if h.Proxy != nil {
+		p := *h.Proxy
+		p.Password = h.proxyPassword()
+		return p.Dial(dialer, network, addr)
+	}
+
+	if h.JumpHost == nil {
+		return dialer.Dial(network, addr)
+	}
+
+	jump, err := h.JumpHost.Dial()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to jump host %s: %w", h.JumpHost, err)
+	}
 
-func MarshalHost(h *host) (string, error) {
-	bytes, err := json.Marshal(h)
+	conn, err := jump.Dial("tcp", addr)
 	if err != nil {
-		return "", err
+		jump.Close()
+		return nil, fmt.Errorf("failed to open tunnel via jump host %s: %w", h.JumpHost, err)
 	}
-	return string(bytes), nil
+
+	return &jumpConn{Conn: conn, jump: jump}, nil
 }
 
-func UnmarshalHost(str string) (*host, error) {
-	h := &host{}
-	err := json.Unmarshal([]byte(str), h)
+// jumpConn is a tunnel through a jump host, which is closed with the tunnel.
+type jumpConn struct {
+	net.Conn
+	jump *ssh.Client
+}
+
+func (c *jumpConn) Close() error {
+	err := c.Conn.Close()
+	c.jump.Close()
+	return err
+}
+
+func (h *host) RunCommand(command string, stdout io.Writer, stderr io.Writer) error {
+	conn, release, err := h.pool.acquire(h)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	defer release()
 
-	return h, nil
+	session, err := conn.NewSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	session.Stdout = stdout
+	session.Stderr = stderr
+
+	stopKeepalive := h.keepalive(conn)
+	err = session.Run(command)
+	if lost := stopKeepalive(); lost != nil {
+		return lost
+	}
+
+	return err
 }
 
 func dataSourceHostRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	c, _ := m.(*providerConfig)
 	h := &host{
-		Port: 22,
+		Port: c.defaultPort(),
 	}
 
 	if j, ok := d.GetOk("extends_host_json"); ok {
@@ -242,6 +1143,58 @@ func dataSourceHostRead(ctx context.Context, d *schema.ResourceData, m interface
 		d.Set("username", h.Username)
 	}
 
+	if ct, ok := d.GetOk("connect_timeout"); ok {
+		h.ConnectTimeout = ct.(string)
+	} else {
+		d.Set("connect_timeout", h.ConnectTimeout)
+	}
+
+	if af, ok := d.GetOk("address_family"); ok {
+		h.AddressFamily = af.(string)
+	} else {
+		d.Set("address_family", h.AddressFamily)
+	}
+
+	if ba, ok := d.GetOk("bind_address"); ok {
+		h.BindAddress = ba.(string)
+	} else {
+		d.Set("bind_address", h.BindAddress)
+	}
+
+	if ap, ok := d.GetOk("algorithm_profile"); ok {
+		h.AlgorithmProfile = ap.(string)
+	} else {
+		d.Set("algorithm_profile", h.AlgorithmProfile)
+	}
+
+	for name, algos := range map[string]*[]string{
+		"ciphers":             &h.Ciphers,
+		"key_exchanges":       &h.KeyExchanges,
+		"macs":                &h.MACs,
+		"host_key_algorithms": &h.HostKeyAlgorithms,
+	} {
+		if as, ok := d.GetOk(name); ok {
+			*algos = nil
+			for _, a := range as.([]interface{}) {
+				*algos = append(*algos, a.(string))
+			}
+		} else {
+			d.Set(name, *algos)
+		}
+	}
+
+	if ki, ok := d.GetOk("keepalive_interval"); ok {
+		h.KeepaliveInterval = ki.(string)
+	} else {
+		d.Set("keepalive_interval", h.KeepaliveInterval)
+	}
+
+	if kc, ok := d.GetOk("keepalive_count_max"); ok {
+		h.KeepaliveCountMax = kc.(int)
+	} else {
+		d.Set("keepalive_count_max", h.KeepaliveCountMax)
+	}
+
 	if pw, ok := d.GetOk("password"); ok {
 		h.Password = pw.(string)
 	} else {
@@ -254,14 +1207,179 @@ func dataSourceHostRead(ctx context.Context, d *schema.ResourceData, m interface
 		d.Set("client_private_key_pem", h.ClientPrivateKeyPem)
 	}
 
+	if pp, ok := d.GetOk("client_private_key_passphrase"); ok {
+		h.ClientPrivateKeyPassphrase = pp.(string)
+	} else {
+		d.Set("client_private_key_passphrase", h.ClientPrivateKeyPassphrase)
+	}
+
+	for _, f := range h.attributeSecretFields() {
+		readSecretSource(d, f)
+	}
+
+	if cert, ok := d.GetOk("client_certificate"); ok {
+		h.ClientCertificate = cert.(string)
+	} else {
+		d.Set("client_certificate", h.ClientCertificate)
+	}
+
+	// GetOk cannot tell false from unset, which would keep
+	// keyboard_interactive of extends_host_json.
+	if ki, ok := d.GetOkExists("keyboard_interactive"); ok {
+		h.KeyboardInteractive = ki.(bool)
+	} else {
+		d.Set("keyboard_interactive", h.KeyboardInteractive)
+	}
+
+	readKeyboardInteractiveAnswers(d, h)
+
+	if ams, ok := d.GetOk("auth_methods"); ok {
+		h.AuthMethods = nil
+		for _, am := range ams.([]interface{}) {
+			h.AuthMethods = append(h.AuthMethods, am.(string))
+		}
+	} else {
+		d.Set("auth_methods", h.AuthMethods)
+	}
+
+	// GetOk cannot tell false from unset, which would keep use_agent of
+	// extends_host_json.
+	if ua, ok := d.GetOkExists("use_agent"); ok {
+		h.UseAgent = ua.(bool)
+	} else {
+		d.Set("use_agent", h.UseAgent)
+	}
+
+	if sock, ok := d.GetOk("agent_socket"); ok {
+		h.AgentSocket = sock.(string)
+	} else {
+		d.Set("agent_socket", h.AgentSocket)
+	}
+
 	if key, ok := d.GetOk("host_publickey_authorized_key"); ok {
 		h.HostPublickeyAuthorizedKey = key.(string)
 	} else {
 		d.Set("host_publickey_authorized_key", h.HostPublickeyAuthorizedKey)
 	}
 
+	if keys, ok := d.GetOk("host_publickey_authorized_keys"); ok {
+		h.HostPublickeyAuthorizedKeys = nil
+		for _, k := range keys.([]interface{}) {
+			h.HostPublickeyAuthorizedKeys = append(h.HostPublickeyAuthorizedKeys, k.(string))
+		}
+	} else {
+		d.Set("host_publickey_authorized_keys", h.HostPublickeyAuthorizedKeys)
+	}
+
+	if cas, ok := d.GetOk("host_ca_authorized_keys"); ok {
+		h.HostCaAuthorizedKeys = cas.(string)
+	} else {
+		d.Set("host_ca_authorized_keys", h.HostCaAuthorizedKeys)
+	}
+
+	if ps, ok := d.GetOk("host_certificate_principals"); ok {
+		h.HostCertificatePrincipals = nil
+		for _, p := range ps.([]interface{}) {
+			h.HostCertificatePrincipals = append(h.HostCertificatePrincipals, p.(string))
+		}
+	} else {
+		d.Set("host_certificate_principals", h.HostCertificatePrincipals)
+	}
+
+	if rev, ok := d.GetOk("host_revoked_keys"); ok {
+		h.HostRevokedKeys = rev.(string)
+	} else {
+		d.Set("host_revoked_keys", h.HostRevokedKeys)
+	}
+
+	if kh, ok := d.GetOk("known_hosts"); ok {
+		h.KnownHosts = kh.(string)
+	} else {
+		d.Set("known_hosts", h.KnownHosts)
+	}
+
+	if khf, ok := d.GetOk("known_hosts_file"); ok {
+		h.KnownHostsFile = khf.(string)
+	} else {
+		d.Set("known_hosts_file", h.KnownHostsFile)
+	}
+
 	h.InsecureIgnoreHostKey = d.Get("insecure_ignore_host_key").(bool)
 
+	if jj, ok := d.GetOk("jump_host_json"); ok {
+		jump, err := UnmarshalHost(jj.(string))
+		if err != nil {
+			return diag.Errorf("failed to parse jump_host_json: %s", err)
+		}
+		h.JumpHost = jump
+	} else if h.JumpHost != nil {
+		jj, err := MarshalHost(h.JumpHost)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		d.Set("jump_host_json", jj)
+	}
+
+	if pc, ok := d.GetOk("proxy_command"); ok {
+		h.ProxyCommand = pc.(string)
+	} else {
+		d.Set("proxy_command", h.ProxyCommand)
+	}
+
+	if rs, ok := d.GetOk("retry"); ok {
+		r := rs.([]interface{})[0].(map[string]interface{})
+		h.Retry = &hostRetry{
+			MaxAttempts:    r["max_attempts"].(int),
+			InitialBackoff: r["initial_backoff"].(string),
+			MaxBackoff:     r["max_backoff"].(string),
+			Jitter:         r["jitter"].(float64),
+			Timeout:        r["timeout"].(string),
+		}
+		for _, e := range r["retryable_errors"].([]interface{}) {
+			h.Retry.RetryableErrors = append(h.Retry.RetryableErrors, e.(string))
+		}
+	} else if h.Retry != nil {
+		retryableErrors := make([]interface{}, 0, len(h.Retry.RetryableErrors))
+		for _, e := range h.Retry.RetryableErrors {
+			retryableErrors = append(retryableErrors, e)
+		}
+		d.Set("retry", []interface{}{map[string]interface{}{
+			"max_attempts":     h.Retry.MaxAttempts,
+			"initial_backoff":  h.Retry.InitialBackoff,
+			"max_backoff":      h.Retry.MaxBackoff,
+			"jitter":           h.Retry.Jitter,
+			"timeout":          h.Retry.Timeout,
+			"retryable_errors": retryableErrors,
+		}})
+	}
+
+	if ps, ok := d.GetOk("proxy"); ok {
+		p := ps.([]interface{})[0].(map[string]interface{})
+		h.Proxy = &hostProxy{
+			Type:     p["type"].(string),
+			Hostname: p["hostname"].(string),
+			Port:     p["port"].(int),
+			Username: p["username"].(string),
+			Password: p["password"].(string),
+		}
+		if pf := p["password_from"].([]interface{}); len(pf) > 0 && pf[0] != nil {
+			h.Proxy.PasswordFrom = newSecretSource(pf[0].(map[string]interface{}))
+		}
+	} else if h.Proxy != nil {
+		var passwordFrom []interface{}
+		if h.Proxy.PasswordFrom != nil {
+			passwordFrom = []interface{}{h.Proxy.PasswordFrom.attributes()}
+		}
+		d.Set("proxy", []interface{}{map[string]interface{}{
+			"type":          h.Proxy.Type,
+			"hostname":      h.Proxy.Hostname,
+			"port":          h.Proxy.Port,
+			"username":      h.Proxy.Username,
+			"password":      h.Proxy.Password,
+			"password_from": passwordFrom,
+		}})
+	}
+
 	j, err := MarshalHost(h)
 	if err != nil {
 		return diag.FromErr(err)
@@ -1,17 +1,72 @@
 package sshclient
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"log"
+	"math"
 	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
 
 	"github.com/google/uuid"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 )
 
+// keyscanDefaultAlgorithms are the host key algorithms scanned if not
+// specified, in order of preference.
+var keyscanDefaultAlgorithms = []string{
+	ssh.KeyAlgoED25519,
+	ssh.KeyAlgoECDSA256,
+	ssh.KeyAlgoECDSA384,
+	ssh.KeyAlgoECDSA521,
+	ssh.KeyAlgoRSASHA512,
+	ssh.KeyAlgoRSASHA256,
+	ssh.KeyAlgoRSA,
+}
+
+// keyscanCertAlgorithms maps key types to the host key algorithms of their
+// certificates in order of preference.
+var keyscanCertAlgorithms = map[string][]string{
+	ssh.KeyAlgoED25519:  {ssh.CertAlgoED25519v01},
+	ssh.KeyAlgoECDSA256: {ssh.CertAlgoECDSA256v01},
+	ssh.KeyAlgoECDSA384: {ssh.CertAlgoECDSA384v01},
+	ssh.KeyAlgoECDSA521: {ssh.CertAlgoECDSA521v01},
+	ssh.KeyAlgoRSA:      {ssh.CertAlgoRSASHA512v01, ssh.CertAlgoRSASHA256v01, ssh.CertAlgoRSAv01},
+	ssh.KeyAlgoDSA:      {ssh.CertAlgoDSAv01},
+}
+
+// keyscanKeyType returns the type of the keys scanned with algorithm, which
+// is the type of the certified key for certificate algorithms.
+func keyscanKeyType(algorithm string) string {
+	for keyType, certAlgorithms := range keyscanCertAlgorithms {
+		if stringInSlice(algorithm, certAlgorithms) {
+			return keyType
+		}
+	}
+	return hostKeyAlgorithmKeyType(algorithm)
+}
+
+var (
+	// errKeyscanDone aborts the handshake once the host key is received.
+	errKeyscanDone = errors.New("host key scanned")
+	// errKeyscanUnsupported means that the server does not offer the host
+	// key algorithm.
+	errKeyscanUnsupported = errors.New("host key algorithm not offered by the server")
+)
+
 func dataSourceKeyscan() *schema.Resource {
 	return &schema.Resource{
 		ReadContext: dataSourceKeyscanRead,
@@ -22,50 +77,580 @@ func dataSourceKeyscan() *schema.Resource {
 				Required:  true,
 				Sensitive: true,
 			},
+			"host_key_algorithms": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "Host key algorithms to scan in order of preference, with one handshake each. Algorithms using a key already scanned, such as ssh-rsa after rsa-sha2-512, are skipped. Algorithms not offered by the server are skipped with a warning if set explicitly. Defaults to ssh-ed25519, ecdsa-sha2-nistp256, ecdsa-sha2-nistp384, ecdsa-sha2-nistp521, rsa-sha2-512, rsa-sha2-256 and ssh-rsa.",
+			},
+			"timeout": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     "1m",
+				Description: "Maximum duration of the whole scan including retries, such as 30s.",
+			},
+			"wait_for_port": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Description: "Retry while the connection is refused or times out, such as while the host is booting, until timeout. This overrides the retry policy of the host.",
+			},
+			"expected_fingerprint": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Fingerprint of the host key obtained out of band, such as from a console log, either SHA256:<base64> or MD5 in hex separated by colons. The read fails unless a scanned key matches it, and only the matched key is returned. insecure_ignore_host_key is not needed with this.",
+			},
+			"hash_known_hosts": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Description: "Hash the hostname in known_hosts_line, like HashKnownHosts of ssh_config(5).",
+			},
 			"authorized_key": {
-				Type:     schema.TypeString,
-				Computed: true,
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Host key of the first algorithm in host_key_algorithms offered by the server. A certified key is reported without its certificate.",
+			},
+			"fingerprint_sha256": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "SHA256 fingerprint of authorized_key, as shown by ssh-keygen -l.",
 			},
+			"fingerprint_md5": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Legacy MD5 fingerprint of authorized_key in hex separated by colons, as shown by ssh-keygen -l -E md5 without the MD5: prefix.",
+			},
+			"known_hosts_line": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Line of known_hosts (sshd(8)) for authorized_key. The hostname is in [host]:port form if the port is not 22.",
+			},
+			"sshfp": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "Values of SSHFP DNS records (RFC 4255) for authorized_key with SHA-1 and SHA-256 fingerprints, such as \"4 2 <hex>\".",
+			},
+			"server_version": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Identification string sent by the server in the handshake of authorized_key, such as SSH-2.0-OpenSSH_8.4.",
+			},
+			"certificate": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Whether the server presents a host certificate for authorized_key, which is checked with a separate handshake using the certificate algorithms of the key type, such as ssh-ed25519-cert-v01@openssh.com.",
+			},
+			"certificate_signing_ca": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Public key of the certificate authority that signed the certificate in authorized_keys format.",
+			},
+			"certificate_principals": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "Principals (hostnames) the certificate is valid for. Empty if valid for any host.",
+			},
+			"certificate_serial": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Serial number of the certificate in decimal.",
+			},
+			"certificate_valid_after": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Start of the validity window of the certificate in RFC 3339 format.",
+			},
+			"certificate_valid_before": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "End of the validity window of the certificate in RFC 3339 format. Empty if the certificate never expires.",
+			},
+			"keys": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "Host keys of all algorithms in host_key_algorithms offered by the server, in the same order.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"type": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"authorized_key": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"fingerprint_sha256": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"fingerprint_md5": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"known_hosts_line": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"sshfp": {
+							Type:     schema.TypeList,
+							Computed: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+						"server_version": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"certificate": {
+							Type:     schema.TypeBool,
+							Computed: true,
+						},
+						"certificate_signing_ca": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"certificate_principals": {
+							Type:     schema.TypeList,
+							Computed: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+						"certificate_serial": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"certificate_valid_after": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"certificate_valid_before": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// keyscanConn records the first bytes received for diagnostics.
+type keyscanConn struct {
+	net.Conn
+
+	mu       sync.Mutex
+	received []byte
+}
+
+const keyscanReceivedMax = 256
+
+func (c *keyscanConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+
+	c.mu.Lock()
+	if rest := keyscanReceivedMax - len(c.received); rest > 0 {
+		if n < rest {
+			rest = n
+		}
+		c.received = append(c.received, b[:rest]...)
+	}
+	c.mu.Unlock()
+
+	return n, err
+}
+
+// banner returns the SSH identification string received, or an empty string
+// if none has been received.
+func (c *keyscanConn) banner() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// The server may send other lines before the banner.
+	for _, line := range bytes.Split(c.received, []byte("\n")) {
+		if bytes.HasPrefix(line, []byte("SSH-")) {
+			return string(bytes.TrimRight(line, "\r"))
+		}
+	}
+	return ""
+}
+
+// diagnose describes err of the handshake by what was received.
+func (c *keyscanConn) diagnose(err error) error {
+	c.mu.Lock()
+	received := c.received
+	c.mu.Unlock()
+
+	if len(received) == 0 {
+		return fmt.Errorf("no SSH banner received: %w", err)
+	}
+	if banner := c.banner(); banner != "" {
+		return fmt.Errorf("handshake failed after banner %q was received: %w", banner, err)
+	}
+	return fmt.Errorf("protocol mismatch, received %q instead of an SSH banner: %w", received, err)
+}
+
+// keyscanDialError describes err of connecting by its kind.
+func keyscanDialError(err error) error {
+	switch classifyDialError(err) {
+	case retryConnectionRefused:
+		return fmt.Errorf("connection refused, nothing is listening on the port: %w", err)
+	case retryTimeout:
+		return fmt.Errorf("timed out connecting: %w", err)
+	case retryUnreachable:
+		return fmt.Errorf("host unreachable: %w", err)
+	}
+	return err
+}
+
+// scannedHostKey is a host key received in a handshake.
+type scannedHostKey struct {
+	// key is the plain host key, which is certified by certificate if the
+	// server presents one.
+	key         ssh.PublicKey
+	certificate *ssh.Certificate
+	// serverVersion is the identification string of the server, such as
+	// SSH-2.0-OpenSSH_8.4.
+	serverVersion string
+}
+
+// scanHostKeyOnce performs a handshake with config and returns the host key.
+// The handshake is aborted when ctx is done.
+func (h *host) scanHostKeyOnce(ctx context.Context, config *ssh.ClientConfig) (*scannedHostKey, error) {
+	type dialResult struct {
+		conn net.Conn
+		err  error
+	}
+	dialed := make(chan dialResult, 1)
+	go func() {
+		conn, err := h.dialConn(h.addr())
+		dialed <- dialResult{conn, err}
+	}()
+
+	var conn net.Conn
+	select {
+	case r := <-dialed:
+		if r.err != nil {
+			return nil, keyscanDialError(r.err)
+		}
+		conn = r.conn
+	case <-ctx.Done():
+		go func() {
+			if r := <-dialed; r.conn != nil {
+				r.conn.Close()
+			}
+		}()
+		return nil, fmt.Errorf("connecting: %w", ctx.Err())
+	}
+	defer conn.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	var key ssh.PublicKey
+	c := *config
+	c.HostKeyCallback = func(hostname string, remote net.Addr, k ssh.PublicKey) error {
+		key = k
+		return errKeyscanDone
+	}
+
+	kc := &keyscanConn{Conn: conn}
+	sc, chans, reqs, err := ssh.NewClientConn(kc, h.addr(), &c)
+	if err == nil {
+		ssh.NewClient(sc, chans, reqs).Close()
+	}
+	if key != nil {
+		// The handshake is aborted before ssh.ConnMetadata is available, so
+		// the server version is taken from what was received.
+		scanned := &scannedHostKey{key: key, serverVersion: kc.banner()}
+		if cert, ok := key.(*ssh.Certificate); ok {
+			scanned.key, scanned.certificate = cert.Key, cert
+		}
+		return scanned, nil
+	}
+
+	if ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	return nil, kc.diagnose(err)
+}
+
+// scanHostKey returns the host key of algorithm from a handshake offering
+// only algorithm, retrying according to the retry policy until ctx is done.
+func (h *host) scanHostKey(ctx context.Context, algorithm string) (*scannedHostKey, error) {
+	algos := h.algorithms()
+	config := &ssh.ClientConfig{
+		Config: ssh.Config{
+			Ciphers:      algos.Ciphers,
+			KeyExchanges: algos.KeyExchanges,
+			MACs:         algos.MACs,
 		},
+		User:              h.Username,
+		HostKeyAlgorithms: []string{algorithm},
+	}
+
+	var scanned *scannedHostKey
+	err := h.withRetryContext(ctx, func() error {
+		var err error
+		scanned, err = h.scanHostKeyOnce(ctx, config)
+		return err
+	})
+	if err != nil && strings.Contains(err.Error(), "no common algorithm for host key") {
+		return nil, errKeyscanUnsupported
+	}
+	return scanned, err
+}
+
+// scanCertificate sets the host certificate of s if the server presents one
+// for s.key, with a handshake for each certificate algorithm of the key type.
+func (h *host) scanCertificate(ctx context.Context, s *scannedHostKey) error {
+	if s.certificate != nil {
+		return nil
+	}
+
+	for _, algorithm := range keyscanCertAlgorithms[s.key.Type()] {
+		c, err := h.scanHostKey(ctx, algorithm)
+		if errors.Is(err, errKeyscanUnsupported) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		// The certificate may be of another key if the server has several
+		// keys of the type.
+		if bytes.Equal(c.key.Marshal(), s.key.Marshal()) {
+			s.certificate = c.certificate
+		}
+		return nil
 	}
+	return nil
+}
+
+// sshfpAlgorithms maps key types to algorithm numbers of SSHFP records.
+var sshfpAlgorithms = map[string]int{
+	ssh.KeyAlgoRSA:      1,
+	ssh.KeyAlgoDSA:      2,
+	ssh.KeyAlgoECDSA256: 3,
+	ssh.KeyAlgoECDSA384: 3,
+	ssh.KeyAlgoECDSA521: 3,
+	ssh.KeyAlgoED25519:  4,
 }
 
-func keyscanCallback(ch chan ssh.PublicKey) ssh.HostKeyCallback {
-	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
-		ch <- key
+// sshfp returns the values of SSHFP records for key with SHA-1 and SHA-256
+// fingerprints, or nil if key has no SSHFP algorithm number.
+func sshfp(key ssh.PublicKey) []interface{} {
+	if cert, ok := key.(*ssh.Certificate); ok {
+		key = cert.Key
+	}
+
+	algorithm, ok := sshfpAlgorithms[key.Type()]
+	if !ok {
 		return nil
 	}
+
+	sha1Sum := sha1.Sum(key.Marshal())
+	sha256Sum := sha256.Sum256(key.Marshal())
+	return []interface{}{
+		fmt.Sprintf("%d 1 %s", algorithm, hex.EncodeToString(sha1Sum[:])),
+		fmt.Sprintf("%d 2 %s", algorithm, hex.EncodeToString(sha256Sum[:])),
+	}
+}
+
+// certificateAttributes returns the attributes describing cert, which is nil
+// if there is no certificate.
+func certificateAttributes(cert *ssh.Certificate) map[string]interface{} {
+	attrs := map[string]interface{}{
+		"certificate":              false,
+		"certificate_signing_ca":   "",
+		"certificate_principals":   []interface{}{},
+		"certificate_serial":       "",
+		"certificate_valid_after":  "",
+		"certificate_valid_before": "",
+	}
+
+	if cert == nil {
+		return attrs
+	}
+
+	var principals []interface{}
+	for _, p := range cert.ValidPrincipals {
+		principals = append(principals, p)
+	}
+	attrs["certificate"] = true
+	attrs["certificate_signing_ca"] = string(ssh.MarshalAuthorizedKey(cert.SignatureKey))
+	attrs["certificate_principals"] = principals
+	attrs["certificate_serial"] = strconv.FormatUint(cert.Serial, 10)
+	attrs["certificate_valid_after"] = time.Unix(int64(cert.ValidAfter), 0).UTC().Format(time.RFC3339)
+	if cert.ValidBefore != ssh.CertTimeInfinity {
+		attrs["certificate_valid_before"] = time.Unix(int64(cert.ValidBefore), 0).UTC().Format(time.RFC3339)
+	}
+	return attrs
+}
+
+// fingerprintMatches reports whether the fingerprint of key is expected.
+// expected is either a SHA256 fingerprint like SHA256:<base64>, or an MD5
+// fingerprint in hex separated by colons with optional MD5: prefix. The format
+// of expected is checked even if key is nil.
+func fingerprintMatches(key ssh.PublicKey, expected string) (bool, error) {
+	switch {
+	case strings.HasPrefix(expected, "SHA256:"):
+		return key != nil && ssh.FingerprintSHA256(key) == strings.TrimRight(expected, "="), nil
+	case md5FingerprintPattern.MatchString(expected):
+		md5 := strings.ToLower(strings.TrimPrefix(expected, "MD5:"))
+		return key != nil && ssh.FingerprintLegacyMD5(key) == md5, nil
+	}
+	return false, fmt.Errorf("invalid expected_fingerprint %q, must be either SHA256:<base64> or MD5 in hex separated by colons", expected)
+}
+
+var md5FingerprintPattern = regexp.MustCompile(`^(MD5:)?[0-9a-fA-F]{2}(:[0-9a-fA-F]{2}){15}$`)
+
+// knownHostsLine returns the known_hosts line of key for h.
+func (h *host) knownHostsLine(key ssh.PublicKey, hash bool) string {
+	hostname := knownhosts.Normalize(h.addr())
+	if hash {
+		hostname = knownhosts.HashHostname(hostname)
+	}
+	return knownhosts.Line([]string{hostname}, key)
 }
 
 func dataSourceKeyscanRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
 	var diags diag.Diagnostics
 
 	hostJson := d.Get("host_json").(string)
-	h, err := UnmarshalHost(hostJson)
+	h, err := unmarshalHostWithDefaults(hostJson, m)
 	if err != nil {
 		return diag.FromErr(err)
 	}
 
-	err = h.validateHostInfo()
+	expected := d.Get("expected_fingerprint").(string)
+	if expected != "" {
+		if _, err := fingerprintMatches(nil, expected); err != nil {
+			return diag.FromErr(err)
+		}
+		// The scanned key is verified with expected_fingerprint instead.
+		if !h.hasHostKeyInfo() {
+			h.InsecureIgnoreHostKey = true
+		}
+	}
+
+	err = h.validate(false)
 	if err != nil {
 		return diag.FromErr(err)
 	}
+	// The keyscan does not authenticate, so only the proxy needs secrets.
+	if err := h.resolveSecrets(h.proxySecretFields()); err != nil {
+		return diag.FromErr(err)
+	}
 
-	if !h.InsecureIgnoreHostKey {
-		return diag.Errorf("To scan host key, insecure_ignore_host_key should be explicitly set.")
+	if !h.InsecureIgnoreHostKey && expected == "" {
+		return diag.Errorf("To scan host key, insecure_ignore_host_key or expected_fingerprint should be explicitly set.")
 	}
 
-	ch := make(chan ssh.PublicKey, 1)
-	config := &ssh.ClientConfig{
-		User:            h.Username,
-		Auth:            []ssh.AuthMethod{},
-		HostKeyCallback: keyscanCallback(ch),
+	algorithms := keyscanDefaultAlgorithms
+	if as, ok := d.GetOk("host_key_algorithms"); ok {
+		algorithms = nil
+		for _, a := range as.([]interface{}) {
+			algorithms = append(algorithms, a.(string))
+		}
+	}
+	if err := validateAlgorithms("host_key_algorithms", algorithms, supportedHostKeyAlgorithms); err != nil {
+		return diag.FromErr(err)
+	}
+
+	timeout, err := time.ParseDuration(d.Get("timeout").(string))
+	if err != nil {
+		return diag.Errorf("invalid timeout: %s", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if d.Get("wait_for_port").(bool) {
+		h.Retry = &hostRetry{
+			MaxAttempts:     math.MaxInt32,
+			MaxBackoff:      "5s",
+			RetryableErrors: retryDefaultErrors,
+		}
 	}
 
-	ssh.Dial("tcp", fmt.Sprintf("%s:%d", h.Hostname, h.Port), config)
+	var scanned []*scannedHostKey
+	var scannedTypes, unsupported []string
+	for _, algorithm := range algorithms {
+		// A key is scanned once even if several algorithms use it, such as
+		// rsa-sha2-512 and ssh-rsa.
+		if stringInSlice(keyscanKeyType(algorithm), scannedTypes) {
+			continue
+		}
+
+		key, err := h.scanHostKey(ctx, algorithm)
+		if errors.Is(err, errKeyscanUnsupported) {
+			log.Printf("[INFO] %s: host key algorithm %s is not offered by the server", h, algorithm)
+			unsupported = append(unsupported, algorithm)
+			continue
+		}
+		if err != nil {
+			return diag.Errorf("%s: failed to scan %s host key: %s", h, algorithm, err)
+		}
+		scanned = append(scanned, key)
+		scannedTypes = append(scannedTypes, key.key.Type())
+	}
+	if len(scanned) == 0 {
+		return diag.Errorf("%s: the server offers none of host key algorithms %s", h, strings.Join(algorithms, ", "))
+	}
+	if _, ok := d.GetOk("host_key_algorithms"); ok && len(unsupported) > 0 {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  "host key algorithms not offered",
+			Detail:   fmt.Sprintf("%s: the server does not offer host key algorithms %s", h, strings.Join(unsupported, ", ")),
+		})
+	}
 
-	pub := <-ch
-	d.Set("authorized_key", string(ssh.MarshalAuthorizedKey(pub)))
+	if expected != "" {
+		var verified []*scannedHostKey
+		var fingerprints []string
+		for _, s := range scanned {
+			if ok, _ := fingerprintMatches(s.key, expected); ok {
+				verified = append(verified, s)
+			}
+			fingerprints = append(fingerprints, fmt.Sprintf("%s %s", s.key.Type(), ssh.FingerprintSHA256(s.key)))
+		}
+		if len(verified) == 0 {
+			return diag.Errorf("%s: no scanned host key matches expected_fingerprint %s, possibly a Man-In-The-Middle attack. Scanned: %s", h, expected, strings.Join(fingerprints, ", "))
+		}
+		scanned = verified
+	}
+
+	for _, s := range scanned {
+		if err := h.scanCertificate(ctx, s); err != nil {
+			return diag.Errorf("%s: failed to scan %s host certificate: %s", h, s.key.Type(), err)
+		}
+	}
+
+	var keys []interface{}
+	for _, s := range scanned {
+		key := certificateAttributes(s.certificate)
+		key["type"] = s.key.Type()
+		key["authorized_key"] = string(ssh.MarshalAuthorizedKey(s.key))
+		key["fingerprint_sha256"] = ssh.FingerprintSHA256(s.key)
+		key["fingerprint_md5"] = ssh.FingerprintLegacyMD5(s.key)
+		key["known_hosts_line"] = h.knownHostsLine(s.key, d.Get("hash_known_hosts").(bool))
+		key["sshfp"] = sshfp(s.key)
+		key["server_version"] = s.serverVersion
+		keys = append(keys, key)
+	}
+
+	preferred := keys[0].(map[string]interface{})
+	for _, k := range []string{
+		"authorized_key", "fingerprint_sha256", "fingerprint_md5", "known_hosts_line", "sshfp", "server_version",
+		"certificate", "certificate_signing_ca", "certificate_principals", "certificate_serial", "certificate_valid_after", "certificate_valid_before",
+	} {
+		d.Set(k, preferred[k])
+	}
+	d.Set("keys", keys)
 
 	id := uuid.New().String()
 	d.SetId(id)
@@ -1,10 +1,27 @@
 package sshclient
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
 )
 
 func TestAccSshclientKeyscan(t *testing.T) {
@@ -33,3 +50,389 @@ func testAccSshclientKeyscanRead(t *testing.T) string {
 		testAccSshclientHostPubkey(t),
 	)
 }
+
+// newTestKeyscanServer starts a test SSH server with ed25519, ECDSA and RSA
+// host keys, and returns it with the signers of the ECDSA and RSA keys.
+func newTestKeyscanServer(t *testing.T) (*testSSHServer, ssh.Signer, ssh.Signer) {
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecdsaSigner, err := ssh.NewSignerFromKey(ecdsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaSigner, err := ssh.NewSignerFromKey(rsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	config.AddHostKey(ecdsaSigner)
+	config.AddHostKey(rsaSigner)
+
+	return newTestSSHServer(t, config), ecdsaSigner, rsaSigner
+}
+
+// testKeyscanRead reads sshclient_keyscan with raw attributes and host_json
+// of an insecure host for s.
+func testKeyscanRead(t *testing.T, s *testSSHServer, raw map[string]interface{}) (*schema.ResourceData, diag.Diagnostics) {
+	h := s.host("keyscan")
+	h.HostPublickeyAuthorizedKey = ""
+	h.InsecureIgnoreHostKey = true
+	j, err := MarshalHost(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw["host_json"] = j
+
+	d := schema.TestResourceDataRaw(t, dataSourceKeyscan().Schema, raw)
+	return d, dataSourceKeyscanRead(context.Background(), d, nil)
+}
+
+func TestDataSourceKeyscanRead(t *testing.T) {
+	s, ecdsaSigner, rsaSigner := newTestKeyscanServer(t)
+	authorizedKey := func(signer ssh.Signer) string {
+		return string(ssh.MarshalAuthorizedKey(signer.PublicKey()))
+	}
+
+	d, diags := testKeyscanRead(t, s, map[string]interface{}{})
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+
+	expected := []struct{ keyType, authorizedKey string }{
+		{ssh.KeyAlgoED25519, authorizedKey(s.hostKey)},
+		{ssh.KeyAlgoECDSA256, authorizedKey(ecdsaSigner)},
+		{ssh.KeyAlgoRSA, authorizedKey(rsaSigner)},
+	}
+	if n := d.Get("keys.#").(int); n != len(expected) {
+		t.Fatalf("unexpected number of keys: %d", n)
+	}
+	for i, e := range expected {
+		if keyType := d.Get(fmt.Sprintf("keys.%d.type", i)); keyType != e.keyType {
+			t.Errorf("Type not match:\n\tIndex: %d\n\tType: %s\n\tExpected: %s", i, keyType, e.keyType)
+		}
+		if key := d.Get(fmt.Sprintf("keys.%d.authorized_key", i)); key != e.authorizedKey {
+			t.Errorf("Key not match:\n\tIndex: %d\n\tKey: %s\n\tExpected: %s", i, key, e.authorizedKey)
+		}
+	}
+	if key := d.Get("authorized_key"); key != authorizedKey(s.hostKey) {
+		t.Errorf("unexpected authorized_key: %s", key)
+	}
+
+	d, diags = testKeyscanRead(t, s, map[string]interface{}{
+		"host_key_algorithms": []interface{}{ssh.KeyAlgoECDSA384, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA},
+	})
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	if key := d.Get("authorized_key"); key != authorizedKey(rsaSigner) {
+		t.Errorf("unexpected authorized_key of preferred algorithm: %s", key)
+	}
+	if n := d.Get("keys.#").(int); n != 1 {
+		t.Errorf("RSA key should be scanned once, but %d keys are returned", n)
+	}
+	if len(diags) != 1 || !strings.Contains(diags[0].Detail, ssh.KeyAlgoECDSA384) {
+		t.Errorf("expected warning for algorithm not offered: %v", diags)
+	}
+
+	_, diags = testKeyscanRead(t, s, map[string]interface{}{
+		"host_key_algorithms": []interface{}{ssh.KeyAlgoECDSA521},
+	})
+	if !diags.HasError() {
+		t.Error("expected error when the server offers no requested algorithm")
+	}
+
+	_, diags = testKeyscanRead(t, s, map[string]interface{}{
+		"host_key_algorithms": []interface{}{"rsa-sha2-1024"},
+	})
+	if !diags.HasError() {
+		t.Error("expected error for unsupported algorithm")
+	}
+}
+
+func TestDataSourceKeyscanReadFingerprints(t *testing.T) {
+	s := newTestSSHServer(t, &ssh.ServerConfig{NoClientAuth: true})
+	key := s.hostKey.PublicKey()
+	addr := s.listener.Addr().(*net.TCPAddr)
+
+	d, diags := testKeyscanRead(t, s, map[string]interface{}{})
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+
+	sum := sha256.Sum256(key.Marshal())
+	expected := map[string]string{
+		"fingerprint_sha256": ssh.FingerprintSHA256(key),
+		"fingerprint_md5":    ssh.FingerprintLegacyMD5(key),
+		"known_hosts_line":   fmt.Sprintf("[%s]:%d %s", addr.IP, addr.Port, strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key)))),
+		"sshfp.1":            "4 2 " + hex.EncodeToString(sum[:]),
+		"keys.0.sshfp.1":     "4 2 " + hex.EncodeToString(sum[:]),
+	}
+	for k, e := range expected {
+		if v := d.Get(k); v != e {
+			t.Errorf("Attribute not match:\n\tAttribute: %s\n\tValue: %v\n\tExpected: %s", k, v, e)
+		}
+	}
+
+	d, diags = testKeyscanRead(t, s, map[string]interface{}{"hash_known_hosts": true})
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	line := d.Get("known_hosts_line").(string)
+	if !strings.HasPrefix(line, "|1|") {
+		t.Errorf("hostname not hashed: %s", line)
+	}
+
+	file := filepath.Join(t.TempDir(), "known_hosts")
+	if err := os.WriteFile(file, []byte(line+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cb, err := knownhosts.New(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cb(addr.String(), addr, key); err != nil {
+		t.Errorf("known_hosts_line not accepted: %s", err)
+	}
+}
+
+func TestDataSourceKeyscanReadExpectedFingerprint(t *testing.T) {
+	s, ecdsaSigner, _ := newTestKeyscanServer(t)
+	key := ecdsaSigner.PublicKey()
+
+	// Without insecure_ignore_host_key, as the fingerprint verifies the key.
+	h := s.host("keyscan")
+	h.HostPublickeyAuthorizedKey = ""
+	hostJson, err := MarshalHost(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name        string
+		fingerprint string
+		success     bool
+	}{
+		{"SHA256", ssh.FingerprintSHA256(key), true},
+		{"SHA256 with padding", ssh.FingerprintSHA256(key) + "=", true},
+		{"MD5", "MD5:" + ssh.FingerprintLegacyMD5(key), true},
+		{"MD5 upper case without prefix", strings.ToUpper(ssh.FingerprintLegacyMD5(key)), true},
+		{"Mismatch", ssh.FingerprintSHA256(testGenerateSigner(t).PublicKey()), false},
+		{"Invalid format", "SHA1:" + hex.EncodeToString(key.Marshal()[:20]), false},
+	}
+	for _, c := range cases {
+		d := schema.TestResourceDataRaw(t, dataSourceKeyscan().Schema, map[string]interface{}{
+			"host_json":            hostJson,
+			"expected_fingerprint": c.fingerprint,
+		})
+		diags := dataSourceKeyscanRead(context.Background(), d, nil)
+		if diags.HasError() == c.success {
+			t.Errorf("Error status not match:\n\tCase: %s\n\tSucceeded?: %v\n\tExpected to succeed?: %v\n\tError: %v", c.name, !diags.HasError(), c.success, diags)
+			continue
+		}
+		if !c.success {
+			continue
+		}
+		if v := d.Get("fingerprint_sha256"); v != ssh.FingerprintSHA256(key) {
+			t.Errorf("unexpected fingerprint_sha256 for %s: %s", c.name, v)
+		}
+		if n := d.Get("keys.#").(int); n != 1 {
+			t.Errorf("unexpected number of keys for %s: %d", c.name, n)
+		}
+	}
+}
+
+func TestDataSourceKeyscanReadCertificate(t *testing.T) {
+	ca := testGenerateSigner(t)
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecdsaSigner, err := ssh.NewSignerFromKey(ecdsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := testSignCert(t, ca, ecdsaSigner.PublicKey(), ssh.HostCert, 1500000000, 1700000000)
+	cert.Serial = 42
+	if err := cert.SignCert(rand.Reader, ca); err != nil {
+		t.Fatal(err)
+	}
+	certSigner, err := ssh.NewCertSigner(cert, ecdsaSigner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := &ssh.ServerConfig{NoClientAuth: true, ServerVersion: "SSH-2.0-OpenSSH_7.4"}
+	config.AddHostKey(ecdsaSigner)
+	config.AddHostKey(certSigner)
+	s := newTestSSHServer(t, config)
+	ecdsaAuthorizedKey := string(ssh.MarshalAuthorizedKey(ecdsaSigner.PublicKey()))
+
+	// Certificates are scanned with the default algorithms, and reported
+	// along with the plain keys.
+	d, diags := testKeyscanRead(t, s, map[string]interface{}{})
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+
+	expected := map[string]interface{}{
+		"server_version":                  "SSH-2.0-OpenSSH_7.4",
+		"certificate":                     false,
+		"certificate_serial":              "",
+		"keys.0.type":                     ssh.KeyAlgoED25519,
+		"keys.0.certificate":              false,
+		"keys.1.type":                     ssh.KeyAlgoECDSA256,
+		"keys.1.authorized_key":           ecdsaAuthorizedKey,
+		"keys.1.fingerprint_sha256":       ssh.FingerprintSHA256(ecdsaSigner.PublicKey()),
+		"keys.1.server_version":           "SSH-2.0-OpenSSH_7.4",
+		"keys.1.certificate":              true,
+		"keys.1.certificate_signing_ca":   string(ssh.MarshalAuthorizedKey(ca.PublicKey())),
+		"keys.1.certificate_principals.0": "certuser",
+		"keys.1.certificate_serial":       "42",
+		"keys.1.certificate_valid_after":  "2017-07-14T02:40:00Z",
+		"keys.1.certificate_valid_before": "2023-11-14T22:13:20Z",
+	}
+	for k, e := range expected {
+		if v := d.Get(k); v != e {
+			t.Errorf("Attribute not match:\n\tAttribute: %s\n\tValue: %v\n\tExpected: %v", k, v, e)
+		}
+	}
+
+	// The plain key is reported even if only the certificate algorithm is
+	// requested.
+	d, diags = testKeyscanRead(t, s, map[string]interface{}{
+		"host_key_algorithms": []interface{}{ssh.CertAlgoECDSA256v01},
+	})
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+
+	expected = map[string]interface{}{
+		"authorized_key":     ecdsaAuthorizedKey,
+		"certificate":        true,
+		"certificate_serial": "42",
+		"keys.0.type":        ssh.KeyAlgoECDSA256,
+	}
+	for k, e := range expected {
+		if v := d.Get(k); v != e {
+			t.Errorf("Attribute not match:\n\tAttribute: %s\n\tValue: %v\n\tExpected: %v", k, v, e)
+		}
+	}
+}
+
+// testKeyscanListener returns a listener whose connections are served by
+// serve.
+func testKeyscanListener(t *testing.T, serve func(conn net.Conn)) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn)
+		}
+	}()
+	return l
+}
+
+func TestDataSourceKeyscanReadErrors(t *testing.T) {
+	httpServer := testKeyscanListener(t, func(conn net.Conn) {
+		defer conn.Close()
+		conn.Write([]byte("HTTP/1.1 400 Bad Request\r\n\r\n"))
+	})
+
+	silentServer := testKeyscanListener(t, func(conn net.Conn) {
+		defer conn.Close()
+		io.Copy(io.Discard, conn)
+	})
+
+	bannerOnlyServer := testKeyscanListener(t, func(conn net.Conn) {
+		defer conn.Close()
+		conn.Write([]byte("SSH-2.0-Broken\r\n"))
+	})
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed.Close()
+
+	cases := []struct {
+		name     string
+		listener net.Listener
+		message  string
+	}{
+		{"protocol mismatch", httpServer, "protocol mismatch"},
+		{"no banner", silentServer, "no SSH banner received: context deadline exceeded"},
+		{"banner received", bannerOnlyServer, `after banner "SSH-2.0-Broken"`},
+		{"refused", closed, "connection refused"},
+	}
+
+	for _, c := range cases {
+		s := &testSSHServer{listener: c.listener, hostKey: testGenerateSigner(t)}
+
+		start := time.Now()
+		_, diags := testKeyscanRead(t, s, map[string]interface{}{"timeout": "500ms"})
+		if !diags.HasError() {
+			t.Errorf("expected error: %s", c.name)
+			continue
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("keyscan did not honor timeout: %s took %s", c.name, elapsed)
+		}
+		if summary := diags[0].Summary; !strings.Contains(summary, c.message) {
+			t.Errorf("Error not match:\n\tCase: %s\n\tError: %s\n\tExpected: %s", c.name, summary, c.message)
+		}
+	}
+}
+
+func TestDataSourceKeyscanReadWaitForPort(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	// The host key is not used because keyscan ignores it.
+	s := &testSSHServer{listener: l, hostKey: testGenerateSigner(t)}
+	started := make(chan *testSSHServer, 1)
+	go func() {
+		time.Sleep(1500 * time.Millisecond)
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			close(started)
+			return
+		}
+		started <- newTestSSHServerWithListener(t, &ssh.ServerConfig{NoClientAuth: true}, l)
+	}()
+
+	d, diags := testKeyscanRead(t, s, map[string]interface{}{
+		"timeout":             "10s",
+		"wait_for_port":       true,
+		"host_key_algorithms": []interface{}{ssh.KeyAlgoED25519},
+	})
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+
+	server := <-started
+	if server == nil {
+		t.Fatal("failed to start the server on the port")
+	}
+	if key := d.Get("authorized_key"); key != string(ssh.MarshalAuthorizedKey(server.hostKey.PublicKey())) {
+		t.Errorf("unexpected authorized_key: %s", key)
+	}
+}
@@ -0,0 +1,505 @@
+package sshclient
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+const sshConfigMaxJumpDepth = 16
+
+func dataSourceSshConfig() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceSshConfigRead,
+		Schema: map[string]*schema.Schema{
+			"config": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "Content of an ssh_config(5) file, such as file(\"~/.ssh/config\"). Include and Match exec are not supported.",
+			},
+			"host": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "Host alias to resolve, as given to ssh(1).",
+			},
+			"hostname": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"port": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"username": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Empty if User is not specified, unlike ssh(1) using the local username.",
+			},
+			"json": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Sensitive:   true,
+				Description: "Host JSON resolved from the config, which can be used as extends_host_json of sshclient_host. IdentityFile is referred to by client_private_key_pem_from so that the key is not stored in state.",
+			},
+		},
+	}
+}
+
+type sshConfigLine struct {
+	keyword string
+	args    []string
+}
+
+type sshConfigBlock struct {
+	// keyword is "host", "match" or empty for lines before the first block.
+	keyword string
+	args    []string
+	lines   []sshConfigLine
+}
+
+// splitSshConfigArgs splits a line into arguments, honoring double quotes.
+func splitSshConfigArgs(line string) ([]string, error) {
+	var args []string
+	var b strings.Builder
+	inArg, quoted := false, false
+	for _, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			inArg = true
+		case !quoted && (r == ' ' || r == '\t'):
+			if inArg {
+				args = append(args, b.String())
+				b.Reset()
+				inArg = false
+			}
+		default:
+			b.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if inArg {
+		args = append(args, b.String())
+	}
+	return args, nil
+}
+
+func parseSshConfig(text string) ([]sshConfigBlock, error) {
+	blocks := []sshConfigBlock{{}}
+	for i, raw := range strings.Split(text, "\n") {
+		line := strings.TrimSpace(raw)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// The keyword may be separated from its arguments by "=".
+		var keyword, rest string
+		if n := strings.IndexAny(line, " \t="); n >= 0 {
+			keyword = line[:n]
+			rest = strings.TrimSpace(line[n:])
+			rest = strings.TrimSpace(strings.TrimPrefix(rest, "="))
+		} else {
+			keyword = line
+		}
+		keyword = strings.ToLower(keyword)
+
+		args, err := splitSshConfigArgs(rest)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		if len(args) == 0 {
+			return nil, fmt.Errorf("line %d: %s has no argument", i+1, keyword)
+		}
+
+		switch keyword {
+		case "host", "match":
+			blocks = append(blocks, sshConfigBlock{keyword: keyword, args: args})
+		case "include":
+			return nil, fmt.Errorf("line %d: Include is not supported", i+1)
+		default:
+			b := &blocks[len(blocks)-1]
+			b.lines = append(b.lines, sshConfigLine{keyword: keyword, args: args})
+		}
+	}
+	return blocks, nil
+}
+
+// sshConfigWildcardMatch matches s against pattern with * and ? wildcards.
+func sshConfigWildcardMatch(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for i := 0; i <= len(s); i++ {
+				if sshConfigWildcardMatch(pattern[1:], s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+		default:
+			if len(s) == 0 || pattern[0] != s[0] {
+				return false
+			}
+		}
+		pattern = pattern[1:]
+		s = s[1:]
+	}
+	return len(s) == 0
+}
+
+// sshConfigPatternListMatch matches s against patterns. Any negated pattern
+// matching s makes the whole list not match.
+func sshConfigPatternListMatch(patterns []string, s string) bool {
+	s = strings.ToLower(s)
+	matched := false
+	for _, p := range patterns {
+		p = strings.ToLower(p)
+		if strings.HasPrefix(p, "!") {
+			if sshConfigWildcardMatch(p[1:], s) {
+				return false
+			}
+		} else if sshConfigWildcardMatch(p, s) {
+			matched = true
+		}
+	}
+	return matched
+}
+
+type sshConfigOption struct {
+	args  []string
+	order int
+}
+
+type sshConfigResolver struct {
+	alias     string
+	localUser string
+	home      string
+	options   map[string]sshConfigOption
+	// identityFiles accumulate like ssh(1) does, unlike other options.
+	identityFiles []string
+}
+
+func (r *sshConfigResolver) get(keyword string) string {
+	if o, ok := r.options[keyword]; ok {
+		return o.args[0]
+	}
+	return ""
+}
+
+func (r *sshConfigResolver) hostname() string {
+	if hn := r.get("hostname"); hn != "" {
+		return strings.NewReplacer("%%", "%", "%h", r.alias).Replace(hn)
+	}
+	return r.alias
+}
+
+// username returns the remote username, which defaults to the local one.
+func (r *sshConfigResolver) username() string {
+	if u := r.get("user"); u != "" {
+		return u
+	}
+	return r.localUser
+}
+
+func (r *sshConfigResolver) matchBlock(b sshConfigBlock) (bool, error) {
+	switch b.keyword {
+	case "":
+		return true, nil
+	case "host":
+		return sshConfigPatternListMatch(b.args, r.alias), nil
+	}
+
+	args := b.args
+	for len(args) > 0 {
+		criterion := strings.ToLower(args[0])
+		negated := strings.HasPrefix(criterion, "!")
+		criterion = strings.TrimPrefix(criterion, "!")
+		args = args[1:]
+
+		var matched bool
+		switch criterion {
+		case "all":
+			matched = true
+		case "host", "originalhost", "user", "localuser":
+			if len(args) == 0 {
+				return false, fmt.Errorf("match %s has no argument", criterion)
+			}
+			patterns := strings.Split(args[0], ",")
+			args = args[1:]
+
+			var value string
+			switch criterion {
+			case "host":
+				value = r.hostname()
+			case "originalhost":
+				value = r.alias
+			case "user":
+				value = r.username()
+			case "localuser":
+				value = r.localUser
+			}
+			matched = sshConfigPatternListMatch(patterns, value)
+		default:
+			return false, fmt.Errorf("match %s is not supported", criterion)
+		}
+
+		if matched == negated {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (r *sshConfigResolver) resolve(blocks []sshConfigBlock) error {
+	order := 0
+	for _, b := range blocks {
+		ok, err := r.matchBlock(b)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		for _, l := range b.lines {
+			order++
+			if l.keyword == "identityfile" {
+				r.identityFiles = append(r.identityFiles, l.args[0])
+				continue
+			}
+			// The first obtained value is used.
+			if _, ok := r.options[l.keyword]; !ok {
+				r.options[l.keyword] = sshConfigOption{args: l.args, order: order}
+			}
+		}
+	}
+	return nil
+}
+
+// expandPath expands ~ and the tokens of ssh_config(5) usable in file paths.
+func (r *sshConfigResolver) expandPath(p string, h *host) string {
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		p = r.home + p[1:]
+	}
+	replacer := strings.NewReplacer(
+		"%%", "%",
+		"%d", r.home,
+		"%h", h.Hostname,
+		"%n", r.alias,
+		"%p", strconv.Itoa(h.Port),
+		"%r", h.Username,
+		"%u", r.localUser,
+	)
+	return replacer.Replace(p)
+}
+
+// firstFile returns the first of paths that exists, expanded.
+func (r *sshConfigResolver) firstFile(paths []string, h *host) (string, error) {
+	var errs []string
+	for _, p := range paths {
+		path := r.expandPath(p, h)
+		_, err := os.Stat(path)
+		if err == nil {
+			return path, nil
+		}
+		errs = append(errs, err.Error())
+	}
+	return "", fmt.Errorf("%s", strings.Join(errs, "; "))
+}
+
+func (r *sshConfigResolver) readFirstFile(paths []string, h *host) (string, error) {
+	path, err := r.firstFile(paths, h)
+	if err != nil {
+		return "", err
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// parseJumpSpec parses [user@]host[:port] or ssh://[user@]host[:port].
+func parseJumpSpec(spec string) (username, hostname string, port int, err error) {
+	if !strings.HasPrefix(spec, "ssh://") {
+		spec = "ssh://" + spec
+	}
+	u, err := url.Parse(spec)
+	if err != nil {
+		return "", "", 0, err
+	}
+	if u.User != nil {
+		username = u.User.Username()
+	}
+	hostname = u.Hostname()
+	if p := u.Port(); p != "" {
+		port, err = strconv.Atoi(p)
+		if err != nil {
+			return "", "", 0, err
+		}
+	}
+	if hostname == "" {
+		return "", "", 0, fmt.Errorf("no hostname in %q", spec)
+	}
+	return username, hostname, port, nil
+}
+
+// sshConfigHost resolves alias in blocks into a host like ssh(1) does.
+func sshConfigHost(blocks []sshConfigBlock, alias string, depth int) (*host, error) {
+	if depth > sshConfigMaxJumpDepth {
+		return nil, fmt.Errorf("too many jump hosts, or ProxyJump loops")
+	}
+
+	localUser := ""
+	if u, err := user.Current(); err == nil {
+		localUser = u.Username
+	}
+	home, _ := os.UserHomeDir()
+
+	r := &sshConfigResolver{
+		alias:     alias,
+		localUser: localUser,
+		home:      home,
+		options:   map[string]sshConfigOption{},
+	}
+	if err := r.resolve(blocks); err != nil {
+		return nil, fmt.Errorf("%s: %w", alias, err)
+	}
+
+	h := &host{
+		Hostname: r.hostname(),
+		Port:     22,
+		Username: r.get("user"),
+	}
+
+	if p := r.get("port"); p != "" {
+		port, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid Port %q", alias, p)
+		}
+		h.Port = port
+	}
+
+	// The private key is referred to by its path so that host_json contains no
+	// secret.
+	if len(r.identityFiles) > 0 {
+		path, err := r.firstFile(r.identityFiles, h)
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to find IdentityFile: %w", alias, err)
+		}
+		h.ClientPrivateKeyPemFrom = &secretSource{File: path}
+	}
+
+	if cert, ok := r.options["certificatefile"]; ok {
+		c, err := r.readFirstFile(cert.args, h)
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to read CertificateFile: %w", alias, err)
+		}
+		h.ClientCertificate = c
+	}
+
+	if agent := r.get("identityagent"); agent != "" && strings.ToLower(agent) != "none" {
+		h.UseAgent = h.ClientPrivateKeyPemFrom == nil
+		if agent != "SSH_AUTH_SOCK" {
+			h.AgentSocket = r.expandPath(agent, h)
+		}
+	}
+
+	if khf := r.get("userknownhostsfile"); khf != "" && strings.ToLower(khf) != "none" {
+		h.KnownHostsFile = r.expandPath(khf, h)
+	}
+
+	switch strings.ToLower(r.get("stricthostkeychecking")) {
+	case "no", "off":
+		h.InsecureIgnoreHostKey = true
+		h.KnownHostsFile = ""
+	}
+
+	// ProxyJump and ProxyCommand are exclusive, and the first obtained is used.
+	jump, okJump := r.options["proxyjump"]
+	command, okCommand := r.options["proxycommand"]
+	if okJump && okCommand {
+		if jump.order < command.order {
+			okCommand = false
+		} else {
+			okJump = false
+		}
+	}
+
+	if okCommand && strings.ToLower(command.args[0]) != "none" {
+		h.ProxyCommand = strings.Join(command.args, " ")
+	}
+
+	if okJump && strings.ToLower(jump.args[0]) != "none" {
+		var prev *host
+		for _, spec := range strings.Split(jump.args[0], ",") {
+			username, hostname, port, err := parseJumpSpec(spec)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid ProxyJump: %w", alias, err)
+			}
+
+			hop, err := sshConfigHost(blocks, hostname, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			if username != "" {
+				hop.Username = username
+			}
+			if port != 0 {
+				hop.Port = port
+			}
+			if prev != nil {
+				hop.JumpHost = prev
+				hop.ProxyCommand = ""
+			}
+			prev = hop
+		}
+		h.JumpHost = prev
+	}
+
+	return h, nil
+}
+
+func dataSourceSshConfigRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	blocks, err := parseSshConfig(d.Get("config").(string))
+	if err != nil {
+		return diag.Errorf("failed to parse config: %s", err)
+	}
+
+	h, err := sshConfigHost(blocks, d.Get("host").(string), 0)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	j, err := MarshalHost(h)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.Set("hostname", h.Hostname)
+	d.Set("port", h.Port)
+	d.Set("username", h.Username)
+	if err := d.Set("json", j); err != nil {
+		return diag.FromErr(err)
+	}
+
+	id := uuid.New().String()
+	d.SetId(id)
+
+	var diags diag.Diagnostics
+	return diags
+}
@@ -0,0 +1,200 @@
+package sshclient
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestAccSshclientSshConfig(t *testing.T) {
+	t.Parallel()
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccSshclientSshConfigRead(t),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.sshclient_ssh_config.web", "hostname", "10.0.0.10"),
+					resource.TestCheckResourceAttr("data.sshclient_ssh_config.web", "port", "2222"),
+					resource.TestCheckResourceAttr("data.sshclient_ssh_config.web", "username", "deploy"),
+					resource.TestCheckResourceAttrSet("data.sshclient_ssh_config.web", "json"),
+				),
+			},
+		},
+	})
+}
+
+func testAccSshclientSshConfigRead(t *testing.T) string {
+	return `
+	data "sshclient_ssh_config" "web" {
+		host   = "web"
+		config = <<-EOT
+			Host web
+				HostName 10.0.0.10
+				Port 2222
+			Host *
+				User deploy
+		EOT
+	}
+	`
+}
+
+func TestSshConfigHost(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "id_web")
+	if err := os.WriteFile(keyPath, []byte("web key"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	bastionKeyPath := filepath.Join(dir, "id_bastion")
+	if err := os.WriteFile(bastionKeyPath, []byte("bastion key"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	config := fmt.Sprintf(`
+# Comment
+User deploy
+
+Host web web.example.com
+	HostName %%h.internal
+	IdentityFile %s/missing
+	IdentityFile "%s"
+	ProxyJump admin@bastion:2200
+
+Host bastion
+	HostName=bastion.example.com
+	IdentityFile %s
+
+Host legacy
+	ProxyCommand nc %%h %%p
+	ProxyJump bastion
+
+Match host *.internal,!db.internal
+	Port 2022
+
+Match originalhost web user deploy
+	UserKnownHostsFile %s/known_hosts_%%n
+
+Match host bastion.example.com
+	StrictHostKeyChecking no
+
+Host *
+	User nobody
+	Port 22
+`, dir, keyPath, bastionKeyPath, dir)
+
+	blocks, err := parseSshConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := sshConfigHost(blocks, "web", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Hostname != "web.internal" || h.Port != 2022 || h.Username != "deploy" {
+		t.Errorf("unexpected host: %s", h)
+	}
+	if h.ClientPrivateKeyPem != "" || h.ClientPrivateKeyPemFrom == nil || h.ClientPrivateKeyPemFrom.File != keyPath {
+		t.Errorf("unexpected private key: %q, %#v", h.ClientPrivateKeyPem, h.ClientPrivateKeyPemFrom)
+	}
+	if j, err := MarshalHost(h); err != nil {
+		t.Fatal(err)
+	} else if strings.Contains(j, "web key") || strings.Contains(j, "bastion key") {
+		t.Errorf("json contains private keys: %s", j)
+	}
+	if h.KnownHostsFile != filepath.Join(dir, "known_hosts_web") {
+		t.Errorf("unexpected known_hosts_file: %q", h.KnownHostsFile)
+	}
+
+	jump := h.JumpHost
+	if jump == nil {
+		t.Fatal("jump host is not resolved")
+	}
+	if jump.Hostname != "bastion.example.com" || jump.Port != 2200 || jump.Username != "admin" {
+		t.Errorf("unexpected jump host: %s", jump)
+	}
+	if jump.ClientPrivateKeyPemFrom == nil || jump.ClientPrivateKeyPemFrom.File != bastionKeyPath || !jump.InsecureIgnoreHostKey {
+		t.Errorf("unexpected jump host auth: %#v", jump)
+	}
+
+	legacy, err := sshConfigHost(blocks, "legacy", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if legacy.ProxyCommand != "nc %h %p" || legacy.JumpHost != nil {
+		t.Errorf("unexpected proxy: %#v", legacy)
+	}
+
+	db, err := sshConfigHost(blocks, "db.internal", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.Port != 22 {
+		t.Errorf("unexpected port for negated pattern: %d", db.Port)
+	}
+}
+
+func TestSshConfigHostErrors(t *testing.T) {
+	cases := []struct {
+		name   string
+		config string
+	}{
+		{
+			name:   "jump loop",
+			config: "Host a\n\tProxyJump b\nHost b\n\tProxyJump a\n",
+		},
+		{
+			name:   "missing identity file",
+			config: "Host a\n\tIdentityFile /nonexistent/id_rsa\n",
+		},
+		{
+			name:   "match exec",
+			config: "Match exec true\n\tUser root\n",
+		},
+		{
+			name:   "include",
+			config: "Include ~/.ssh/config.d/*\n",
+		},
+		{
+			name:   "invalid port",
+			config: "Host a\n\tPort ssh\n",
+		},
+	}
+	for _, c := range cases {
+		blocks, err := parseSshConfig(c.config)
+		if err == nil {
+			_, err = sshConfigHost(blocks, "a", 0)
+		}
+		if err == nil {
+			t.Errorf("%s: expected an error", c.name)
+		}
+	}
+}
+
+func TestSshConfigPatternListMatch(t *testing.T) {
+	cases := []struct {
+		patterns []string
+		input    string
+		expected bool
+	}{
+		{patterns: []string{"*"}, input: "web", expected: true},
+		{patterns: []string{"web?"}, input: "web1", expected: true},
+		{patterns: []string{"web?"}, input: "web10"},
+		{patterns: []string{"*.example.com"}, input: "WEB.example.com", expected: true},
+		{patterns: []string{"*", "!db*"}, input: "db1"},
+		{patterns: []string{"!db*"}, input: "web"},
+	}
+	for _, c := range cases {
+		if r := sshConfigPatternListMatch(c.patterns, c.input); r != c.expected {
+			t.Errorf(`Output not match:
+	Case:     %v %v
+	Actual:   %v
+	Expected: %v`, c.patterns, c.input, r, c.expected)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package sshclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// hostJSONMigrations[i] migrates the fields of host_json from version i to
+// i+1. Unversioned host_json is version 0.
+var hostJSONMigrations = []func(fields map[string]json.RawMessage) error{
+	// Version 1 only adds version to the fields of unversioned host_json.
+	func(fields map[string]json.RawMessage) error { return nil },
+}
+
+// hostJSONVersion is the version of host_json written by MarshalHost.
+var hostJSONVersion = len(hostJSONMigrations)
+
+func MarshalHost(h *host) (string, error) {
+	for j := h; j != nil; j = j.JumpHost {
+		j.Version = hostJSONVersion
+	}
+
+	bytes, err := json.Marshal(h)
+	if err != nil {
+		return "", err
+	}
+	return string(bytes), nil
+}
+
+// UnmarshalHost migrates host_json of any older version and decodes it,
+// rejecting unknown fields.
+func UnmarshalHost(str string) (*host, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(str), &fields); err != nil {
+		return nil, fmt.Errorf("invalid host_json: %w", err)
+	}
+	if fields == nil {
+		return nil, fmt.Errorf("invalid host_json: must be an object")
+	}
+
+	if err := migrateHostJSON(fields); err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+
+	h := &host{}
+	if err := dec.Decode(h); err != nil {
+		return nil, fmt.Errorf("invalid host_json: %w", err)
+	}
+
+	return h, nil
+}
+
+// migrateHostJSON migrates fields and those of its jump hosts to
+// hostJSONVersion.
+func migrateHostJSON(fields map[string]json.RawMessage) error {
+	version := 0
+	if v, ok := fields["version"]; ok && string(v) != "null" {
+		if err := json.Unmarshal(v, &version); err != nil {
+			return fmt.Errorf("invalid host_json version: %w", err)
+		}
+	}
+	if version < 0 || version > hostJSONVersion {
+		return fmt.Errorf("host_json version %d is not supported, the latest supported version is %d; upgrade the provider", version, hostJSONVersion)
+	}
+
+	for ; version < hostJSONVersion; version++ {
+		if err := hostJSONMigrations[version](fields); err != nil {
+			return fmt.Errorf("failed to migrate host_json from version %d: %w", version, err)
+		}
+	}
+	fields["version"] = json.RawMessage(strconv.Itoa(version))
+
+	if j, ok := fields["jump_host"]; ok && string(j) != "null" {
+		var jump map[string]json.RawMessage
+		if err := json.Unmarshal(j, &jump); err != nil {
+			return fmt.Errorf("invalid host_json jump_host: %w", err)
+		}
+		if err := migrateHostJSON(jump); err != nil {
+			return fmt.Errorf("jump host: %w", err)
+		}
+
+		b, err := json.Marshal(jump)
+		if err != nil {
+			return err
+		}
+		fields["jump_host"] = b
+	}
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package sshclient
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMarshalHost(t *testing.T) {
+	h := &host{
+		Hostname: "target",
+		Port:     22,
+		Username: "user",
+		JumpHost: &host{Hostname: "bastion", Port: 2222, Username: "jump"},
+	}
+
+	j, err := MarshalHost(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(j, `"version":1`) {
+		t.Errorf("version not marshaled: %s", j)
+	}
+
+	unmarshaled, err := UnmarshalHost(j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unmarshaled.Version != hostJSONVersion || unmarshaled.JumpHost.Version != hostJSONVersion {
+		t.Errorf("unexpected versions: %d, %d", unmarshaled.Version, unmarshaled.JumpHost.Version)
+	}
+	if unmarshaled.JumpHost.Hostname != "bastion" || unmarshaled.JumpHost.Port != 2222 {
+		t.Errorf("unexpected jump host: %#v", unmarshaled.JumpHost)
+	}
+}
+
+func TestUnmarshalHost(t *testing.T) {
+	cases := []struct {
+		name    string
+		json    string
+		success bool
+	}{
+		{"unversioned", `{"hostname":"target","port":22,"username":"user"}`, true},
+		{"unversioned jump host", `{"hostname":"target","jump_host":{"hostname":"bastion"}}`, true},
+		{"current", `{"version":1,"hostname":"target"}`, true},
+		{"null jump host", `{"version":1,"hostname":"target","jump_host":null}`, true},
+		{"newer", `{"version":2,"hostname":"target"}`, false},
+		{"negative", `{"version":-1,"hostname":"target"}`, false},
+		{"invalid version", `{"version":"1","hostname":"target"}`, false},
+		{"unknown field", `{"version":1,"hostnme":"target"}`, false},
+		{"unknown field in jump host", `{"hostname":"target","jump_host":{"hostnme":"bastion"}}`, false},
+		{"unknown field in proxy", `{"hostname":"target","proxy":{"typ":"socks5"}}`, false},
+		{"null", `null`, false},
+		{"not an object", `"target"`, false},
+	}
+
+	for _, c := range cases {
+		h, err := UnmarshalHost(c.json)
+		if succeeded := err == nil; succeeded != c.success {
+			t.Errorf("Error status not match:\n\tCase: %s\n\tSucceeded?: %v\n\tExpected to succeed?: %v\n\tError: %v", c.name, succeeded, c.success, err)
+			continue
+		}
+		if err == nil && h.Version != hostJSONVersion {
+			t.Errorf("Version not migrated:\n\tCase: %s\n\tVersion: %d", c.name, h.Version)
+		}
+	}
+}
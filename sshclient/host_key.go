@@ -0,0 +1,296 @@
+package sshclient
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// parseAuthorizedKeys parses every key in authorized_keys (sshd(8)) format,
+// skipping empty lines and comments.
+func parseAuthorizedKeys(in string) ([]ssh.PublicKey, error) {
+	var keys []ssh.PublicKey
+	rest := []byte(in)
+	for len(bytes.TrimSpace(rest)) > 0 {
+		key, _, _, r, err := ssh.ParseAuthorizedKey(rest)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+		rest = r
+	}
+	return keys, nil
+}
+
+func containsKey(keys []ssh.PublicKey, key ssh.PublicKey) bool {
+	b := key.Marshal()
+	for _, k := range keys {
+		if bytes.Equal(k.Marshal(), b) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *host) hostKeyCallback() (ssh.HostKeyCallback, error) {
+	if h.InsecureIgnoreHostKey {
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+
+	revoked, err := parseAuthorizedKeys(h.HostRevokedKeys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse host_revoked_keys: %w", err)
+	}
+
+	var cbs []ssh.HostKeyCallback
+	var pinned []ssh.PublicKey
+	if h.HostPublickeyAuthorizedKey != "" {
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(h.HostPublickeyAuthorizedKey))
+		if err != nil {
+			return nil, err
+		}
+		pinned = append(pinned, key)
+	}
+	for i, k := range h.HostPublickeyAuthorizedKeys {
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(k))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse host_publickey_authorized_keys[%d]: %w", i, err)
+		}
+		pinned = append(pinned, key)
+	}
+	if len(pinned) > 0 {
+		cbs = append(cbs, pinnedHostKeysCallback(pinned))
+	}
+
+	if h.HostCaAuthorizedKeys != "" {
+		cas, err := parseAuthorizedKeys(h.HostCaAuthorizedKeys)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse host_ca_authorized_keys: %w", err)
+		}
+		cbs = append(cbs, hostCertCallback(cas, revoked, h.HostCertificatePrincipals))
+	}
+
+	if h.KnownHosts != "" {
+		cb, err := knownHostsCallback(h.KnownHosts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse known_hosts: %w", err)
+		}
+		cbs = append(cbs, cb)
+	}
+
+	if h.KnownHostsFile != "" {
+		cb, err := knownhosts.New(h.KnownHostsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read known_hosts_file: %w", err)
+		}
+		cbs = append(cbs, cb)
+	}
+
+	if len(cbs) == 0 {
+		return nil, fmt.Errorf("no host key verification is configured")
+	}
+
+	return revokedKeyCallback(revoked, anyHostKeyCallback(cbs)), nil
+}
+
+// hostKeyTypes returns the types of the pinned keys and the keys known for h in
+// known_hosts, or nil if there are none.
+func (h *host) hostKeyTypes() ([]string, error) {
+	if h.InsecureIgnoreHostKey {
+		return nil, nil
+	}
+
+	var types []string
+	add := func(t string) {
+		if !stringInSlice(t, types) {
+			types = append(types, t)
+		}
+	}
+
+	var pinned []string
+	if h.HostPublickeyAuthorizedKey != "" {
+		pinned = append(pinned, h.HostPublickeyAuthorizedKey)
+	}
+	pinned = append(pinned, h.HostPublickeyAuthorizedKeys...)
+	for _, k := range pinned {
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(k))
+		if err != nil {
+			return nil, err
+		}
+		add(key.Type())
+	}
+
+	var cbs []ssh.HostKeyCallback
+	if h.KnownHosts != "" {
+		cb, err := knownHostsCallback(h.KnownHosts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse known_hosts: %w", err)
+		}
+		cbs = append(cbs, cb)
+	}
+	if h.KnownHostsFile != "" {
+		cb, err := knownhosts.New(h.KnownHostsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read known_hosts_file: %w", err)
+		}
+		cbs = append(cbs, cb)
+	}
+	for _, cb := range cbs {
+		// knownhosts reports the keys known for the host when the key does
+		// not match, so probe it with a key that is never known.
+		err := cb(h.addr(), &net.TCPAddr{}, knownHostsProbeKey)
+		var keyErr *knownhosts.KeyError
+		if errors.As(err, &keyErr) {
+			for _, k := range keyErr.Want {
+				add(k.Key.Type())
+			}
+		}
+	}
+
+	return types, nil
+}
+
+// knownHostsProbeKey is an ed25519 key of all zeros, which is not in any
+// known_hosts.
+var knownHostsProbeKey, _ = ssh.NewPublicKey(ed25519.PublicKey(make([]byte, ed25519.PublicKeySize)))
+
+// preferHostKeyAlgorithms moves the algorithms of types to the front of algos
+// like OpenSSH does, so that the server presents a key that can be verified.
+func preferHostKeyAlgorithms(algos, types []string) []string {
+	var preferred, rest []string
+	for _, a := range algos {
+		if stringInSlice(hostKeyAlgorithmKeyType(a), types) {
+			preferred = append(preferred, a)
+		} else {
+			rest = append(rest, a)
+		}
+	}
+	return append(preferred, rest...)
+}
+
+// pinnedHostKeysCallback accepts any of keys.
+func pinnedHostKeysCallback(keys []ssh.PublicKey) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		if !containsKey(keys, key) {
+			return fmt.Errorf("host key %s %s is not pinned", key.Type(), ssh.FingerprintSHA256(key))
+		}
+		return nil
+	}
+}
+
+// knownHostsCallback verifies host keys against known_hosts (sshd(8)) content.
+func knownHostsCallback(content string) (ssh.HostKeyCallback, error) {
+	// knownhosts only reads from files.
+	f, err := os.CreateTemp("", "known_hosts")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	if _, err := f.WriteString(content); err != nil {
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+
+	return knownhosts.New(f.Name())
+}
+
+// anyHostKeyCallback accepts a host key if any of cbs accepts it. A key
+// revoked in known_hosts is rejected even if another callback accepts it.
+func anyHostKeyCallback(cbs []ssh.HostKeyCallback) ssh.HostKeyCallback {
+	if len(cbs) == 1 {
+		return cbs[0]
+	}
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		var accepted bool
+		var errs []string
+		for _, cb := range cbs {
+			err := cb(hostname, remote, key)
+			var revoked *knownhosts.RevokedError
+			if errors.As(err, &revoked) {
+				return err
+			}
+			if err == nil {
+				accepted = true
+			} else {
+				errs = append(errs, err.Error())
+			}
+		}
+		if accepted {
+			return nil
+		}
+		return fmt.Errorf("host key is not trusted: %s", strings.Join(errs, "; "))
+	}
+}
+
+// hostCertCallback accepts host certificates signed by any of cas. If
+// principals is empty, the hostname is checked against the certificate
+// principals like OpenSSH does.
+func hostCertCallback(cas, revoked []ssh.PublicKey, principals []string) ssh.HostKeyCallback {
+	checker := &ssh.CertChecker{
+		IsHostAuthority: func(auth ssh.PublicKey, address string) bool {
+			return containsKey(cas, auth) && !containsKey(revoked, auth)
+		},
+		IsRevoked: func(cert *ssh.Certificate) bool {
+			return containsKey(revoked, cert) || containsKey(revoked, cert.Key)
+		},
+	}
+
+	if len(principals) == 0 {
+		return checker.CheckHostKey
+	}
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		if _, ok := key.(*ssh.Certificate); !ok {
+			return checker.CheckHostKey(hostname, remote, key)
+		}
+
+		var errs []string
+		for _, p := range principals {
+			// CheckHostKey checks the host part of the address as principal.
+			err := checker.CheckHostKey(net.JoinHostPort(p, "0"), remote, key)
+			if err == nil {
+				return nil
+			}
+			errs = append(errs, err.Error())
+		}
+		return fmt.Errorf("no principal accepted: %s", strings.Join(errs, "; "))
+	}
+}
+
+// recordHostKey keeps the host key accepted by cb so that it can be reported
+// by MatchedHostKey.
+func (h *host) recordHostKey(cb ssh.HostKeyCallback) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		if err := cb(hostname, remote, key); err != nil {
+			return err
+		}
+		h.matchedHostKey = key
+		return nil
+	}
+}
+
+// revokedKeyCallback rejects revoked keys before passing them to cb.
+func revokedKeyCallback(revoked []ssh.PublicKey, cb ssh.HostKeyCallback) ssh.HostKeyCallback {
+	if len(revoked) == 0 {
+		return cb
+	}
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		if containsKey(revoked, key) {
+			return fmt.Errorf("host key is revoked")
+		}
+		return cb(hostname, remote, key)
+	}
+}
@@ -0,0 +1,315 @@
+package sshclient
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func TestHostKeyCallbackWithCertificateAuthority(t *testing.T) {
+	ca := testGenerateSigner(t)
+	otherCa := testGenerateSigner(t)
+	hostKey := testGenerateSigner(t)
+	plainKey := testGenerateSigner(t)
+
+	signHost := func(ca ssh.Signer, principals ...string) *ssh.Certificate {
+		cert := testSignCert(t, ca, hostKey.PublicKey(), ssh.HostCert, 0, ssh.CertTimeInfinity)
+		cert.ValidPrincipals = principals
+		if err := cert.SignCert(rand.Reader, ca); err != nil {
+			t.Fatal(err)
+		}
+		return cert
+	}
+	revokedCert := signHost(ca, "example.com")
+	revokedCert.Serial = 42
+	if err := revokedCert.SignCert(rand.Reader, ca); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name       string
+		host       host
+		key        ssh.PublicKey
+		accepted   bool
+		remoteHost string
+	}{
+		{
+			name:     "signed for hostname",
+			host:     host{HostCaAuthorizedKeys: string(ssh.MarshalAuthorizedKey(ca.PublicKey()))},
+			key:      signHost(ca, "example.com"),
+			accepted: true,
+		},
+		{
+			name: "signed for other hostname",
+			host: host{HostCaAuthorizedKeys: string(ssh.MarshalAuthorizedKey(ca.PublicKey()))},
+			key:  signHost(ca, "other.example.com"),
+		},
+		{
+			name: "signed for principal",
+			host: host{
+				HostCaAuthorizedKeys:      string(ssh.MarshalAuthorizedKey(ca.PublicKey())),
+				HostCertificatePrincipals: []string{"web", "other.example.com"},
+			},
+			key:      signHost(ca, "other.example.com"),
+			accepted: true,
+		},
+		{
+			name: "signed by multiple authorities",
+			host: host{HostCaAuthorizedKeys: string(ssh.MarshalAuthorizedKey(otherCa.PublicKey())) +
+				string(ssh.MarshalAuthorizedKey(ca.PublicKey()))},
+			key:      signHost(ca, "example.com"),
+			accepted: true,
+		},
+		{
+			name: "signed by unknown authority",
+			host: host{HostCaAuthorizedKeys: string(ssh.MarshalAuthorizedKey(otherCa.PublicKey()))},
+			key:  signHost(ca, "example.com"),
+		},
+		{
+			name: "revoked authority",
+			host: host{
+				HostCaAuthorizedKeys: string(ssh.MarshalAuthorizedKey(ca.PublicKey())),
+				HostRevokedKeys:      string(ssh.MarshalAuthorizedKey(ca.PublicKey())),
+			},
+			key: signHost(ca, "example.com"),
+		},
+		{
+			name: "revoked certificate",
+			host: host{
+				HostCaAuthorizedKeys: string(ssh.MarshalAuthorizedKey(ca.PublicKey())),
+				HostRevokedKeys:      string(ssh.MarshalAuthorizedKey(revokedCert)),
+			},
+			key: revokedCert,
+		},
+		{
+			name: "plain key without fallback",
+			host: host{HostCaAuthorizedKeys: string(ssh.MarshalAuthorizedKey(ca.PublicKey()))},
+			key:  plainKey.PublicKey(),
+		},
+		{
+			name: "plain key with fallback",
+			host: host{
+				HostCaAuthorizedKeys:       string(ssh.MarshalAuthorizedKey(ca.PublicKey())),
+				HostPublickeyAuthorizedKey: string(ssh.MarshalAuthorizedKey(plainKey.PublicKey())),
+			},
+			key:      plainKey.PublicKey(),
+			accepted: true,
+		},
+		{
+			name: "revoked plain key",
+			host: host{
+				HostPublickeyAuthorizedKey: string(ssh.MarshalAuthorizedKey(plainKey.PublicKey())),
+				HostRevokedKeys:            string(ssh.MarshalAuthorizedKey(plainKey.PublicKey())),
+			},
+			key: plainKey.PublicKey(),
+		},
+	}
+	for _, c := range cases {
+		cb, err := c.host.hostKeyCallback()
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		err = cb("example.com:22", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 22}, c.key)
+		if (err == nil) != c.accepted {
+			t.Errorf(`%s: error status not match:
+	Error:                %v
+	Expected to succeed?: %v`, c.name, err, c.accepted)
+		}
+	}
+}
+
+func TestHostKeyCallbackWithKnownHosts(t *testing.T) {
+	hostKey := testGenerateSigner(t)
+	otherKey := testGenerateSigner(t)
+	ca := testGenerateSigner(t)
+	cert := testSignCert(t, ca, hostKey.PublicKey(), ssh.HostCert, 0, ssh.CertTimeInfinity)
+	cert.ValidPrincipals = []string{"example.com"}
+	if err := cert.SignCert(rand.Reader, ca); err != nil {
+		t.Fatal(err)
+	}
+
+	pub := hostKey.PublicKey()
+	remote := &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 22}
+
+	cases := []struct {
+		name       string
+		knownHosts string
+		address    string
+		key        ssh.PublicKey
+		accepted   bool
+	}{
+		{
+			name:       "plain hostname",
+			knownHosts: knownhosts.Line([]string{"example.com"}, pub),
+			address:    "example.com:22",
+			key:        pub,
+			accepted:   true,
+		},
+		{
+			name:       "other key",
+			knownHosts: knownhosts.Line([]string{"example.com"}, otherKey.PublicKey()),
+			address:    "example.com:22",
+			key:        pub,
+		},
+		{
+			name:       "hashed hostname",
+			knownHosts: knownhosts.Line([]string{knownhosts.HashHostname("example.com")}, pub),
+			address:    "example.com:22",
+			key:        pub,
+			accepted:   true,
+		},
+		{
+			name:       "non-default port",
+			knownHosts: knownhosts.Line([]string{"example.com:2222"}, pub),
+			address:    "example.com:2222",
+			key:        pub,
+			accepted:   true,
+		},
+		{
+			name:       "default port entry for non-default port",
+			knownHosts: knownhosts.Line([]string{"example.com"}, pub),
+			address:    "example.com:2222",
+			key:        pub,
+		},
+		{
+			name: "revoked",
+			knownHosts: knownhosts.Line([]string{"example.com"}, pub) + "\n" +
+				"@revoked * " + string(ssh.MarshalAuthorizedKey(pub)),
+			address: "example.com:22",
+			key:     pub,
+		},
+		{
+			name:       "cert authority",
+			knownHosts: "@cert-authority *.com " + string(ssh.MarshalAuthorizedKey(ca.PublicKey())),
+			address:    "example.com:22",
+			key:        cert,
+			accepted:   true,
+		},
+	}
+	for _, c := range cases {
+		h := &host{KnownHosts: c.knownHosts}
+		cb, err := h.hostKeyCallback()
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		err = cb(c.address, remote, c.key)
+		if (err == nil) != c.accepted {
+			t.Errorf(`%s: error status not match:
+	Error:                %v
+	Expected to succeed?: %v`, c.name, err, c.accepted)
+		}
+	}
+}
+
+func TestHostKeyCallbackWithKnownHostsFile(t *testing.T) {
+	hostKey := testGenerateSigner(t)
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	line := knownhosts.Line([]string{"example.com"}, hostKey.PublicKey()) + "\n"
+	if err := os.WriteFile(path, []byte(line), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	h := &host{
+		Hostname:       "example.com",
+		Port:           22,
+		Username:       "user",
+		KnownHostsFile: path,
+	}
+	if err := h.validateHostInfo(); err != nil {
+		t.Fatal(err)
+	}
+
+	cb, err := h.hostKeyCallback()
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote := &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 22}
+	if err := cb("example.com:22", remote, hostKey.PublicKey()); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestHostRunCommandPrefersKnownHostKeyTypes(t *testing.T) {
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecdsaSigner, err := ssh.NewSignerFromKey(ecdsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	config.AddHostKey(ecdsaSigner)
+	// The server also has an ed25519 key, which is less preferred than ECDSA
+	// by default.
+	s := newTestSSHServer(t, config)
+	knownHosts := knownhosts.Line([]string{knownhosts.Normalize(s.listener.Addr().String())}, s.hostKey.PublicKey())
+
+	cases := []struct {
+		name  string
+		setup func(h *host)
+	}{
+		{"pinned", func(h *host) {}},
+		{"known_hosts", func(h *host) {
+			h.HostPublickeyAuthorizedKey = ""
+			h.KnownHosts = knownHosts
+		}},
+	}
+	for _, c := range cases {
+		h := s.host("user")
+		h.Password = "unused"
+		c.setup(h)
+
+		var stdout, stderr bytes.Buffer
+		if err := h.RunCommand("echo hi", &stdout, &stderr); err != nil {
+			t.Errorf("%s: %v", c.name, err)
+		}
+	}
+}
+
+func TestHostRunCommandWithPinnedHostKeys(t *testing.T) {
+	s := newTestSSHServer(t, &ssh.ServerConfig{NoClientAuth: true})
+	other := testGenerateSigner(t)
+
+	h := s.host("user")
+	h.Password = "unused"
+	hostKey := h.HostPublickeyAuthorizedKey
+	h.HostPublickeyAuthorizedKey = ""
+	h.HostPublickeyAuthorizedKeys = []string{
+		string(ssh.MarshalAuthorizedKey(other.PublicKey())),
+		hostKey,
+	}
+
+	if err := h.validateHostInfo(); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if err := h.RunCommand("echo hi", &stdout, &stderr); err != nil {
+		t.Fatal(err)
+	}
+	if h.MatchedHostKey() != hostKey {
+		t.Errorf(`Matched host key not match:
+	Actual:   %v
+	Expected: %v`, h.MatchedHostKey(), hostKey)
+	}
+
+	h.HostPublickeyAuthorizedKeys = h.HostPublickeyAuthorizedKeys[:1]
+	h.matchedHostKey = nil
+	if err := h.RunCommand("echo hi", &stdout, &stderr); err == nil {
+		t.Error("expected unpinned host key to be rejected")
+	}
+	if h.MatchedHostKey() != "" {
+		t.Errorf("unexpected matched host key: %v", h.MatchedHostKey())
+	}
+}
@@ -0,0 +1,81 @@
+package sshclient
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const keepaliveDefaultCountMax = 3
+
+func (h *host) keepaliveInterval() (time.Duration, error) {
+	return parseOptionalDuration("keepalive_interval", h.KeepaliveInterval, 0)
+}
+
+// keepalive sends keepalive@openssh.com requests on client every
+// keepalive_interval, like ServerAliveInterval of ssh_config(5), and closes
+// client once keepalive_count_max requests in a row are unanswered. The
+// returned function stops sending and returns the error if the connection was
+// lost.
+func (h *host) keepalive(client *ssh.Client) func() error {
+	interval, _ := h.keepaliveInterval()
+	if interval <= 0 {
+		return func() error { return nil }
+	}
+
+	countMax := h.KeepaliveCountMax
+	if countMax <= 0 {
+		countMax = keepaliveDefaultCountMax
+	}
+
+	var (
+		mu         sync.Mutex
+		unanswered int
+		lost       error
+	)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-t.C:
+			}
+
+			mu.Lock()
+			n := unanswered
+			unanswered++
+			mu.Unlock()
+
+			if n >= countMax {
+				lost = fmt.Errorf("connection lost: no response to %d keepalives sent every %s", n, interval)
+				client.Close()
+				return
+			}
+
+			go func() {
+				// Any reply, even a failure, shows that the server is alive.
+				if _, _, err := client.SendRequest("keepalive@openssh.com", true, nil); err == nil {
+					mu.Lock()
+					unanswered = 0
+					mu.Unlock()
+				}
+			}()
+		}
+	}()
+
+	return func() error {
+		close(stop)
+		<-done
+		return lost
+	}
+}
@@ -0,0 +1,105 @@
+package sshclient
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// newTestSilentSSHServer starts an SSH server that accepts exec requests but
+// never completes them nor answers global requests, like a server whose
+// connection was silently dropped after the command started.
+func newTestSilentSSHServer(t *testing.T) *testSSHServer {
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	hostKey := testGenerateSigner(t)
+	config.AddHostKey(hostKey)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+
+				_, chans, reqs, err := ssh.NewServerConn(conn, config)
+				if err != nil {
+					return
+				}
+				go func() {
+					for range reqs {
+					}
+				}()
+
+				for nc := range chans {
+					ch, reqs, err := nc.Accept()
+					if err != nil {
+						return
+					}
+					defer ch.Close()
+					go func() {
+						for req := range reqs {
+							req.Reply(req.Type == "exec", nil)
+						}
+					}()
+				}
+			}()
+		}
+	}()
+
+	return &testSSHServer{listener: l, hostKey: hostKey}
+}
+
+func TestHostKeepalive(t *testing.T) {
+	server := newTestSilentSSHServer(t)
+	h := server.host("user")
+	h.Password = "password"
+	h.KeepaliveInterval = "50ms"
+	h.KeepaliveCountMax = 2
+
+	done := make(chan error, 1)
+	go func() {
+		var stdout, stderr bytes.Buffer
+		done <- h.RunCommand("sleep infinity", &stdout, &stderr)
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "connection lost") {
+			t.Errorf("Error not match:\n\tError: %v\n\tExpected: connection lost", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("RunCommand did not return after keepalives were unanswered")
+	}
+}
+
+func TestHostKeepaliveAnswered(t *testing.T) {
+	server := newTestSSHServer(t, &ssh.ServerConfig{NoClientAuth: true})
+	h := server.host("user")
+	h.Password = "password"
+	h.KeepaliveInterval = "10ms"
+	h.KeepaliveCountMax = 1
+
+	client, err := h.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	stop := h.keepalive(client)
+	time.Sleep(100 * time.Millisecond)
+	if err := stop(); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+}
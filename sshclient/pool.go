@@ -0,0 +1,178 @@
+package sshclient
+
+import (
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// connPool shares connections among resources connecting to the same host so
+// that each of them does not need its own handshake.
+type connPool struct {
+	maxSessions int
+	idleTimeout time.Duration
+
+	mu    sync.Mutex
+	conns map[[sha256.Size]byte][]*pooledConn
+}
+
+type pooledConn struct {
+	// ready is closed when the connection is established or failed.
+	ready          chan struct{}
+	client         *ssh.Client
+	matchedHostKey ssh.PublicKey
+	authMethod     string
+	err            error
+
+	// The following fields are guarded by connPool.mu.
+	sessions  int
+	closed    bool
+	idleTimer *time.Timer
+}
+
+func newConnPool(maxSessions int, idleTimeout time.Duration) *connPool {
+	return &connPool{
+		maxSessions: maxSessions,
+		idleTimeout: idleTimeout,
+		conns:       map[[sha256.Size]byte][]*pooledConn{},
+	}
+}
+
+// poolKey identifies the connection settings of h, including its credentials
+// and jump hosts.
+func poolKey(h *host) ([sha256.Size]byte, error) {
+	j, err := MarshalHost(h)
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	return sha256.Sum256([]byte(j)), nil
+}
+
+// acquire returns a client connected to h with a session reserved for the
+// caller. The returned function must be called once the session is closed.
+// If p is nil, a dedicated connection is used.
+func (p *connPool) acquire(h *host) (*ssh.Client, func(), error) {
+	if p == nil || p.maxSessions <= 0 || p.idleTimeout <= 0 {
+		client, err := h.Dial()
+		if err != nil {
+			return nil, nil, err
+		}
+		return client, func() { client.Close() }, nil
+	}
+
+	key, err := poolKey(h)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p.mu.Lock()
+	var pc *pooledConn
+	for _, c := range p.conns[key] {
+		if !c.closed && c.sessions < p.maxSessions {
+			pc = c
+			break
+		}
+	}
+	dial := pc == nil
+	if dial {
+		pc = &pooledConn{ready: make(chan struct{})}
+		p.conns[key] = append(p.conns[key], pc)
+	}
+	pc.sessions++
+	if pc.idleTimer != nil {
+		pc.idleTimer.Stop()
+		pc.idleTimer = nil
+	}
+	p.mu.Unlock()
+
+	if dial {
+		pc.client, pc.err = h.Dial()
+		pc.matchedHostKey = h.matchedHostKey
+		pc.authMethod = h.authenticatedMethod
+		close(pc.ready)
+
+		if pc.err != nil {
+			p.remove(key, pc)
+		} else {
+			go func() {
+				pc.client.Wait()
+				p.remove(key, pc)
+			}()
+		}
+	}
+
+	<-pc.ready
+	release := func() { p.release(key, pc) }
+	if pc.err != nil {
+		release()
+		return nil, nil, pc.err
+	}
+
+	h.matchedHostKey = pc.matchedHostKey
+	h.authenticatedMethod = pc.authMethod
+	return pc.client, release, nil
+}
+
+func (p *connPool) release(key [sha256.Size]byte, pc *pooledConn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pc.sessions--
+	if pc.sessions > 0 || pc.closed {
+		return
+	}
+
+	pc.idleTimer = time.AfterFunc(p.idleTimeout, func() {
+		// pc is removed in the same critical section as the check so that
+		// acquire cannot pick it up before it is closed.
+		p.mu.Lock()
+		idle := pc.sessions == 0 && !pc.closed
+		if idle {
+			p.removeLocked(key, pc)
+		}
+		p.mu.Unlock()
+
+		if idle {
+			pc.client.Close()
+		}
+	})
+}
+
+// remove stops new sessions from using pc.
+func (p *connPool) remove(key [sha256.Size]byte, pc *pooledConn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.removeLocked(key, pc)
+}
+
+// removeLocked is remove with p.mu held.
+func (p *connPool) removeLocked(key [sha256.Size]byte, pc *pooledConn) {
+	pc.closed = true
+	conns := p.conns[key]
+	for i, c := range conns {
+		if c == pc {
+			conns = append(conns[:i], conns[i+1:]...)
+			break
+		}
+	}
+	if len(conns) == 0 {
+		delete(p.conns, key)
+	} else {
+		p.conns[key] = conns
+	}
+}
+
+// size returns the number of connections in the pool.
+func (p *connPool) size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	n := 0
+	for _, conns := range p.conns {
+		n += len(conns)
+	}
+	return n
+}
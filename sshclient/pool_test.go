@@ -0,0 +1,109 @@
+package sshclient
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestConnPool(t *testing.T) {
+	var handshakes int32
+	s := newTestSSHServer(t, &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			atomic.AddInt32(&handshakes, 1)
+			return nil, nil
+		},
+	})
+
+	pool := newConnPool(5, 100*time.Millisecond)
+	newHost := func() *host {
+		h := s.host("user")
+		h.Password = "secret"
+		h.pool = pool
+		return h
+	}
+
+	for i := 0; i < 3; i++ {
+		var stdout, stderr bytes.Buffer
+		if err := newHost().RunCommand("echo hi", &stdout, &stderr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := atomic.LoadInt32(&handshakes); n != 1 {
+		t.Errorf("sequential runs should share a connection, but %d handshakes happened", n)
+	}
+
+	atomic.StoreInt32(&handshakes, 0)
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			h := newHost()
+			var stdout, stderr bytes.Buffer
+			if err := h.RunCommand(fmt.Sprintf("echo %d", i), &stdout, &stderr); err != nil {
+				errs <- err
+				return
+			}
+			if stdout.String() != fmt.Sprintf("echo %d", i) {
+				errs <- fmt.Errorf("unexpected stdout: %q", stdout.String())
+			}
+			if h.MatchedHostKey() == "" {
+				errs <- fmt.Errorf("matched host key is not reported on shared connection")
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	if n := atomic.LoadInt32(&handshakes); n > 4 {
+		t.Errorf("20 sessions should need up to 4 connections, but %d handshakes happened", n)
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	if n := pool.size(); n != 0 {
+		t.Errorf("idle connections should be closed, but %d remain", n)
+	}
+
+	other := newHost()
+	other.Password = "other"
+	atomic.StoreInt32(&handshakes, 0)
+	for _, h := range []*host{newHost(), other} {
+		var stdout, stderr bytes.Buffer
+		if err := h.RunCommand("echo hi", &stdout, &stderr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := atomic.LoadInt32(&handshakes); n != 2 {
+		t.Errorf("hosts with different settings should not share a connection, but %d handshakes happened", n)
+	}
+}
+
+func TestConnPoolDialError(t *testing.T) {
+	s := newTestSSHServer(t, &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			return nil, fmt.Errorf("wrong password")
+		},
+	})
+
+	pool := newConnPool(5, time.Minute)
+	h := s.host("user")
+	h.Password = "wrong"
+	h.pool = pool
+
+	var stdout, stderr bytes.Buffer
+	if err := h.RunCommand("echo hi", &stdout, &stderr); err == nil {
+		t.Error("expected authentication failure")
+	}
+	if n := pool.size(); n != 0 {
+		t.Errorf("failed connections should not be pooled, but %d remain", n)
+	}
+}
@@ -1,19 +1,201 @@
 package sshclient
 
 import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 )
 
 // Provider -
 func Provider() *schema.Provider {
 	return &schema.Provider{
+		Schema: map[string]*schema.Schema{
+			"username": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Default username for hosts without username.",
+			},
+			"port": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Description: "Default port for hosts without port. This is also the default port of sshclient_host instead of 22.",
+			},
+			"password": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Sensitive:   true,
+				Description: "Default password for hosts without any authentication.",
+			},
+			"client_private_key_pem": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Sensitive:   true,
+				Description: "Default client private key in PEM format for hosts without any authentication.",
+			},
+			"client_private_key_passphrase": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Sensitive:   true,
+				Description: "Passphrase to decrypt the default client_private_key_pem.",
+			},
+			"use_agent": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Description: "Authenticate hosts without any authentication with the ssh-agent.",
+			},
+			"agent_socket": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Path to the ssh-agent socket used with use_agent. If not specified, SSH_AUTH_SOCK is used.",
+			},
+			"connect_timeout": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Default connect_timeout for hosts without connect_timeout.",
+			},
+			"known_hosts": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Default known_hosts content for hosts without any host key verification.",
+			},
+			"known_hosts_file": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Default known_hosts file for hosts without any host key verification.",
+			},
+			"host_ca_authorized_keys": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Default host certificate authorities for hosts without any host key verification.",
+			},
+			"insecure_ignore_host_key": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Description: "Insecurely trust the host public key of hosts without any host key verification.",
+			},
+			"max_sessions_per_connection": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Default:     10,
+				Description: "Maximum number of concurrent sessions on a connection shared among resources. More connections are opened if exceeded. This should not exceed MaxSessions of sshd_config(5).",
+			},
+			"connection_idle_timeout": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     "0s",
+				Description: "Duration to keep a shared connection without sessions open. Connections are shared among resources only if this is set to a positive duration such as 30s.",
+			},
+		},
 		ResourcesMap: map[string]*schema.Resource{
 			"sshclient_run":     resourceRun(),
 			"sshclient_scp_put": resourceScpPut(),
 		},
 		DataSourcesMap: map[string]*schema.Resource{
-			"sshclient_host":    dataSourceHost(),
-			"sshclient_keyscan": dataSourceKeyscan(),
+			"sshclient_host":       dataSourceHost(),
+			"sshclient_keyscan":    dataSourceKeyscan(),
+			"sshclient_ssh_config": dataSourceSshConfig(),
+		},
+		ConfigureContextFunc: providerConfigure,
+	}
+}
+
+type providerConfig struct {
+	// defaults holds the connection settings for fields not set in host JSON.
+	defaults host
+	pool     *connPool
+}
+
+func providerConfigure(ctx context.Context, d *schema.ResourceData) (interface{}, diag.Diagnostics) {
+	c := &providerConfig{
+		defaults: host{
+			Username:                   d.Get("username").(string),
+			Port:                       d.Get("port").(int),
+			Password:                   d.Get("password").(string),
+			ClientPrivateKeyPem:        d.Get("client_private_key_pem").(string),
+			ClientPrivateKeyPassphrase: d.Get("client_private_key_passphrase").(string),
+			UseAgent:                   d.Get("use_agent").(bool),
+			AgentSocket:                d.Get("agent_socket").(string),
+			ConnectTimeout:             d.Get("connect_timeout").(string),
+			KnownHosts:                 d.Get("known_hosts").(string),
+			KnownHostsFile:             d.Get("known_hosts_file").(string),
+			HostCaAuthorizedKeys:       d.Get("host_ca_authorized_keys").(string),
+			InsecureIgnoreHostKey:      d.Get("insecure_ignore_host_key").(bool),
 		},
 	}
+
+	if _, err := c.defaults.connectTimeout(); err != nil {
+		return nil, diag.FromErr(err)
+	}
+
+	idleTimeout, err := time.ParseDuration(d.Get("connection_idle_timeout").(string))
+	if err != nil {
+		return nil, diag.FromErr(fmt.Errorf("invalid connection_idle_timeout: %w", err))
+	}
+	c.pool = newConnPool(d.Get("max_sessions_per_connection").(int), idleTimeout)
+
+	var diags diag.Diagnostics
+	return c, diags
+}
+
+// defaultPort returns the port for hosts without port.
+func (c *providerConfig) defaultPort() int {
+	if c != nil && c.defaults.Port != 0 {
+		return c.defaults.Port
+	}
+	return 22
+}
+
+// apply fills the fields of h not set with the defaults, including its jump
+// hosts. Authentication and host key verification are filled as a whole only
+// if h has none of them, so that they never conflict.
+func (c *providerConfig) apply(h *host) {
+	if c == nil {
+		return
+	}
+	d := &c.defaults
+	h.pool = c.pool
+
+	for ; h != nil; h = h.JumpHost {
+		if h.Username == "" {
+			h.Username = d.Username
+		}
+		if h.Port == 0 {
+			h.Port = d.Port
+		}
+		if h.ConnectTimeout == "" {
+			h.ConnectTimeout = d.ConnectTimeout
+		}
+
+		if !h.hasAuthInfo() {
+			h.Password = d.Password
+			h.ClientPrivateKeyPem = d.ClientPrivateKeyPem
+			h.ClientPrivateKeyPassphrase = d.ClientPrivateKeyPassphrase
+			h.UseAgent = d.UseAgent
+			h.AgentSocket = d.AgentSocket
+		}
+
+		if !h.hasHostKeyInfo() {
+			h.KnownHosts = d.KnownHosts
+			h.KnownHostsFile = d.KnownHostsFile
+			h.HostCaAuthorizedKeys = d.HostCaAuthorizedKeys
+			h.InsecureIgnoreHostKey = d.InsecureIgnoreHostKey
+		}
+	}
+}
+
+// unmarshalHostWithDefaults unmarshals host JSON and fills it with the
+// provider defaults in m.
+func unmarshalHostWithDefaults(str string, m interface{}) (*host, error) {
+	h, err := UnmarshalHost(str)
+	if err != nil {
+		return nil, err
+	}
+
+	c, _ := m.(*providerConfig)
+	c.apply(h)
+
+	return h, nil
 }
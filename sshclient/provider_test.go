@@ -2,13 +2,20 @@ package sshclient
 
 import (
 	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
 	"fmt"
+	"io"
+	"net"
 	"os"
+	"strconv"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
 	"github.com/joho/godotenv"
+	"golang.org/x/crypto/ssh"
 )
 
 var testAccProviders map[string]*schema.Provider
@@ -49,6 +56,50 @@ func TestProvider(t *testing.T) {
 	}
 }
 
+func TestProviderConfigure(t *testing.T) {
+	p := Provider()
+	diags := p.Configure(context.Background(), terraform.NewResourceConfigRaw(map[string]interface{}{
+		"username":                 "deploy",
+		"port":                     2222,
+		"password":                 "default_secret",
+		"connect_timeout":          "5s",
+		"insecure_ignore_host_key": true,
+	}))
+	if diags.HasError() {
+		t.Fatalf("err: %v", diags)
+	}
+	c := p.Meta().(*providerConfig)
+
+	h, err := unmarshalHostWithDefaults(`{"hostname":"11.22.33.44","client_private_key_pem":"pem","host_publickey_authorized_key":"key"}`, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Username != "deploy" || h.Port != 2222 || h.ConnectTimeout != "5s" {
+		t.Errorf("defaults are not applied: %s", h)
+	}
+	if h.Password != "" || h.InsecureIgnoreHostKey {
+		t.Errorf("defaults conflict with host settings: %#v", h)
+	}
+
+	h, err = unmarshalHostWithDefaults(`{"hostname":"11.22.33.44","port":22,"username":"foobar","jump_host":{"hostname":"bastion"}}`, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Username != "foobar" || h.Port != 22 || h.Password != "default_secret" || !h.InsecureIgnoreHostKey {
+		t.Errorf("unexpected host: %#v", h)
+	}
+	if h.JumpHost.Username != "deploy" || h.JumpHost.Port != 2222 || h.JumpHost.Password != "default_secret" {
+		t.Errorf("defaults are not applied to jump host: %#v", h.JumpHost)
+	}
+
+	diags = Provider().Configure(context.Background(), terraform.NewResourceConfigRaw(map[string]interface{}{
+		"connect_timeout": "five seconds",
+	}))
+	if !diags.HasError() {
+		t.Error("expected invalid connect_timeout to be rejected")
+	}
+}
+
 func getTestEnvs() map[string]struct{} {
 	return map[string]struct{}{
 		"TEST_PW_SSH_HOST":     {},
@@ -78,3 +129,149 @@ func testAccPreCheck(t *testing.T) {
 		}
 	}
 }
+
+func testGenerateKey(t *testing.T) ed25519.PrivateKey {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func testGenerateSigner(t *testing.T) ssh.Signer {
+	signer, err := ssh.NewSignerFromKey(testGenerateKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer
+}
+
+// testSSHServer is an in-process SSH server. Each exec request is answered by
+// writing the command itself back to stdout and exiting with status 0.
+type testSSHServer struct {
+	listener net.Listener
+	hostKey  ssh.Signer
+}
+
+func newTestSSHServer(t *testing.T, config *ssh.ServerConfig) *testSSHServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newTestSSHServerWithListener(t, config, l)
+}
+
+func newTestSSHServerWithListener(t *testing.T, config *ssh.ServerConfig, l net.Listener) *testSSHServer {
+	hostKey := testGenerateSigner(t)
+	config.AddHostKey(hostKey)
+
+	t.Cleanup(func() { l.Close() })
+
+	s := &testSSHServer{
+		listener: l,
+		hostKey:  hostKey,
+	}
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn, config)
+		}
+	}()
+
+	return s
+}
+
+func (s *testSSHServer) serve(conn net.Conn, config *ssh.ServerConfig) {
+	defer conn.Close()
+
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	for nc := range chans {
+		if nc.ChannelType() == "direct-tcpip" {
+			go s.forward(nc)
+			continue
+		}
+		if nc.ChannelType() != "session" {
+			nc.Reject(ssh.UnknownChannelType, "unknown channel type")
+			continue
+		}
+		ch, reqs, err := nc.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer ch.Close()
+			for req := range reqs {
+				if req.Type != "exec" {
+					req.Reply(false, nil)
+					continue
+				}
+				var payload struct{ Command string }
+				if err := ssh.Unmarshal(req.Payload, &payload); err != nil {
+					req.Reply(false, nil)
+					continue
+				}
+				req.Reply(true, nil)
+				ch.Write([]byte(payload.Command))
+
+				status := make([]byte, 4)
+				binary.BigEndian.PutUint32(status, 0)
+				ch.SendRequest("exit-status", false, status)
+				return
+			}
+		}()
+	}
+}
+
+// forward serves a direct-tcpip channel so that s can be used as a jump host.
+func (s *testSSHServer) forward(nc ssh.NewChannel) {
+	var payload struct {
+		Host       string
+		Port       uint32
+		OriginHost string
+		OriginPort uint32
+	}
+	if err := ssh.Unmarshal(nc.ExtraData(), &payload); err != nil {
+		nc.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+
+	conn, err := net.Dial("tcp", net.JoinHostPort(payload.Host, strconv.Itoa(int(payload.Port))))
+	if err != nil {
+		nc.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	defer conn.Close()
+
+	ch, reqs, err := nc.Accept()
+	if err != nil {
+		return
+	}
+	defer ch.Close()
+	go ssh.DiscardRequests(reqs)
+
+	go func() {
+		io.Copy(ch, conn)
+		ch.CloseWrite()
+	}()
+	io.Copy(conn, ch)
+}
+
+// host returns a host pointing at s that trusts its host key.
+func (s *testSSHServer) host(username string) *host {
+	addr := s.listener.Addr().(*net.TCPAddr)
+	return &host{
+		Hostname:                   addr.IP.String(),
+		Port:                       addr.Port,
+		Username:                   username,
+		HostPublickeyAuthorizedKey: string(ssh.MarshalAuthorizedKey(s.hostKey.PublicKey())),
+	}
+}
@@ -0,0 +1,134 @@
+package sshclient
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+
+	"golang.org/x/net/proxy"
+)
+
+const (
+	proxyTypeSocks5 = "socks5"
+	proxyTypeHttp   = "http"
+)
+
+type hostProxy struct {
+	Type     string `json:"type"`
+	Hostname string `json:"hostname"`
+	Port     int    `json:"port"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	// PasswordFrom is the source of Password, resolved by the host.
+	PasswordFrom *secretSource `json:"password_from"`
+}
+
+func (p *hostProxy) String() string {
+	return fmt.Sprintf("%s://%s", p.Type, joinHostPort(p.Hostname, p.Port))
+}
+
+func (p *hostProxy) validate() error {
+	if p.Type != proxyTypeSocks5 && p.Type != proxyTypeHttp {
+		return fmt.Errorf("proxy type must be %s or %s", proxyTypeSocks5, proxyTypeHttp)
+	}
+
+	if p.Hostname == "" {
+		return fmt.Errorf("proxy hostname is not provided")
+	}
+
+	if p.Port < tcpPortMin || p.Port > tcpPortMax {
+		return fmt.Errorf("proxy port number out of range. %d", p.Port)
+	}
+
+	if (p.Password != "" || p.PasswordFrom != nil) && p.Username == "" {
+		return fmt.Errorf("proxy password needs proxy username")
+	}
+
+	return nil
+}
+
+// Dial connects to addr through the proxy, connecting to the proxy with dialer
+// over network.
+func (p *hostProxy) Dial(dialer *net.Dialer, network, addr string) (net.Conn, error) {
+	proxyAddr := joinHostPort(p.Hostname, p.Port)
+
+	var conn net.Conn
+	var err error
+	switch p.Type {
+	case proxyTypeSocks5:
+		var auth *proxy.Auth
+		if p.Username != "" {
+			auth = &proxy.Auth{
+				User:     p.Username,
+				Password: p.Password,
+			}
+		}
+
+		var socks proxy.Dialer
+		socks, err = proxy.SOCKS5(network, proxyAddr, auth, dialer)
+		if err == nil {
+			conn, err = socks.Dial("tcp", addr)
+		}
+	case proxyTypeHttp:
+		conn, err = p.dialHttpConnect(dialer, network, proxyAddr, addr)
+	default:
+		err = fmt.Errorf("unknown proxy type %q", p.Type)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect via proxy %s: %w", p, err)
+	}
+	return conn, nil
+}
+
+func (p *hostProxy) dialHttpConnect(dialer *net.Dialer, network, proxyAddr, addr string) (net.Conn, error) {
+	conn, err := dialer.Dial(network, proxyAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: http.Header{},
+	}
+	if p.Username != "" {
+		cred := base64.StdEncoding.EncodeToString([]byte(p.Username + ":" + p.Password))
+		req.Header.Set("Proxy-Authorization", "Basic "+cred)
+	}
+
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	br := bufio.NewReader(conn)
+	// The body is not read because the tunnel follows the response header.
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy refused CONNECT: %s", resp.Status)
+	}
+
+	return &bufferedConn{Conn: conn, r: br}, nil
+}
+
+// bufferedConn reads through r so that bytes buffered while reading the
+// proxy response are not lost.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
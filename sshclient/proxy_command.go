@@ -0,0 +1,133 @@
+package sshclient
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// expandProxyCommand substitutes %h, %p, %r and %% in command like ssh_config(5).
+func (h *host) expandProxyCommand(command string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(command); i++ {
+		if command[i] != '%' {
+			b.WriteByte(command[i])
+			continue
+		}
+
+		i++
+		if i == len(command) {
+			return "", fmt.Errorf("proxy_command ends with incomplete token %%")
+		}
+		switch command[i] {
+		case 'h':
+			b.WriteString(h.Hostname)
+		case 'p':
+			b.WriteString(strconv.Itoa(h.Port))
+		case 'r':
+			b.WriteString(h.Username)
+		case '%':
+			b.WriteByte('%')
+		default:
+			return "", fmt.Errorf("proxy_command has unknown token %%%c", command[i])
+		}
+	}
+	return b.String(), nil
+}
+
+// shellCommand returns a command running command with the shell.
+func shellCommand(command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", command)
+	}
+	return exec.Command("sh", "-c", command)
+}
+
+// dialProxyCommand starts the proxy command and returns a connection over its
+// stdin and stdout.
+func (h *host) dialProxyCommand() (net.Conn, error) {
+	command, err := h.expandProxyCommand(h.ProxyCommand)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := shellCommand(command)
+	cmd.Stderr = os.Stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start proxy_command: %w", err)
+	}
+
+	return &commandConn{
+		cmd:    cmd,
+		stdin:  stdin,
+		stdout: stdout,
+	}, nil
+}
+
+// commandConn is a net.Conn over stdin and stdout of a process.
+type commandConn struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout io.ReadCloser
+
+	closeOnce sync.Once
+}
+
+func (c *commandConn) Read(b []byte) (int, error) {
+	return c.stdout.Read(b)
+}
+
+func (c *commandConn) Write(b []byte) (int, error) {
+	return c.stdin.Write(b)
+}
+
+// Close stops the process. It may be called concurrently by the client and its
+// transport.
+func (c *commandConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.stdin.Close()
+		c.stdout.Close()
+		c.cmd.Process.Kill()
+		c.cmd.Wait()
+	})
+	return nil
+}
+
+// LocalAddr and RemoteAddr return the zero address like the channels of
+// direct-tcpip do, since host key callbacks such as knownhosts parse it.
+func (c *commandConn) LocalAddr() net.Addr {
+	return &net.TCPAddr{}
+}
+
+func (c *commandConn) RemoteAddr() net.Addr {
+	return &net.TCPAddr{}
+}
+
+func (c *commandConn) SetDeadline(t time.Time) error {
+	return fmt.Errorf("deadline is not supported by proxy_command")
+}
+
+func (c *commandConn) SetReadDeadline(t time.Time) error {
+	return c.SetDeadline(t)
+}
+
+func (c *commandConn) SetWriteDeadline(t time.Time) error {
+	return c.SetDeadline(t)
+}
@@ -0,0 +1,129 @@
+package sshclient
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// TestHelperProxyCommand is not a real test but a proxy command run by
+// TestHostRunCommandWithProxyCommand, which connects stdin and stdout to the
+// address given in the arguments.
+func TestHelperProxyCommand(t *testing.T) {
+	if os.Getenv("TEST_HELPER_PROXY_COMMAND") != "1" {
+		return
+	}
+
+	args := os.Args
+	for len(args) > 0 && args[0] != "--" {
+		args = args[1:]
+	}
+	if len(args) != 2 {
+		fmt.Fprintln(os.Stderr, "address is not given")
+		os.Exit(2)
+	}
+
+	conn, err := net.Dial("tcp", args[1])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	go func() {
+		io.Copy(conn, os.Stdin)
+		conn.Close()
+	}()
+	io.Copy(os.Stdout, conn)
+	os.Exit(0)
+}
+
+func TestHostRunCommandWithProxyCommand(t *testing.T) {
+	s := newTestSSHServer(t, &ssh.ServerConfig{NoClientAuth: true})
+
+	h := s.host("user")
+	h.Password = "unused"
+	h.ProxyCommand = fmt.Sprintf("TEST_HELPER_PROXY_COMMAND=1 '%s' -test.run=TestHelperProxyCommand -- %%h:%%p", os.Args[0])
+
+	if err := h.validateHostInfo(); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if err := h.RunCommand("echo hi", &stdout, &stderr); err != nil {
+		t.Fatal(err)
+	}
+	if stdout.String() != "echo hi" {
+		t.Errorf("unexpected stdout: %q", stdout.String())
+	}
+}
+
+func TestHostRunCommandWithProxyCommandAndKnownHosts(t *testing.T) {
+	s := newTestSSHServer(t, &ssh.ServerConfig{NoClientAuth: true})
+
+	h := s.host("user")
+	h.Password = "unused"
+	h.HostPublickeyAuthorizedKey = ""
+	h.KnownHosts = knownhosts.Line([]string{knownhosts.Normalize(s.listener.Addr().String())}, s.hostKey.PublicKey())
+	h.ProxyCommand = fmt.Sprintf("TEST_HELPER_PROXY_COMMAND=1 '%s' -test.run=TestHelperProxyCommand -- %%h:%%p", os.Args[0])
+
+	if err := h.validateHostInfo(); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if err := h.RunCommand("echo hi", &stdout, &stderr); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHostExpandProxyCommand(t *testing.T) {
+	h := &host{
+		Hostname: "example.com",
+		Port:     2222,
+		Username: "foobar",
+	}
+
+	cases := []struct {
+		input    string
+		accepted bool
+		expected string
+	}{
+		{
+			input:    "nc %h %p",
+			accepted: true,
+			expected: "nc example.com 2222",
+		},
+		{
+			input:    "ssm start-session --target %h --user %r --rate 100%%",
+			accepted: true,
+			expected: "ssm start-session --target example.com --user foobar --rate 100%",
+		},
+		{
+			input: "nc %h %x",
+		},
+		{
+			input: "nc %h %",
+		},
+	}
+	for _, c := range cases {
+		r, err := h.expandProxyCommand(c.input)
+		if (err == nil) != c.accepted {
+			t.Errorf(`Error status not match:
+	Case:                 %#v
+	Succeeded?:           %v
+	Expected to succeed?: %v`, c.input, err == nil, c.accepted)
+			continue
+		}
+		if err == nil && r != c.expected {
+			t.Errorf(`Output not match:
+	Case:     %#v
+	Actual:   %v
+	Expected: %v`, c.input, r, c.expected)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package sshclient
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func testServeTunnel(client net.Conn, addr string) {
+	defer client.Close()
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	go io.Copy(conn, client)
+	io.Copy(client, conn)
+}
+
+// newTestSocks5Proxy starts a SOCKS5 proxy accepting the username and
+// password authentication, and returns its address.
+func newTestSocks5Proxy(t *testing.T, username, password string) *net.TCPAddr {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	serve := func(conn net.Conn) {
+		r := bufio.NewReader(conn)
+		buf := make([]byte, 262)
+
+		// Greeting.
+		if _, err := io.ReadFull(r, buf[:2]); err != nil {
+			conn.Close()
+			return
+		}
+		if _, err := io.ReadFull(r, buf[:buf[1]]); err != nil {
+			conn.Close()
+			return
+		}
+		conn.Write([]byte{0x05, 0x02})
+
+		// Username and password.
+		readString := func() string {
+			if _, err := io.ReadFull(r, buf[:1]); err != nil {
+				return ""
+			}
+			n := buf[0]
+			if _, err := io.ReadFull(r, buf[:n]); err != nil {
+				return ""
+			}
+			return string(buf[:n])
+		}
+		if _, err := io.ReadFull(r, buf[:1]); err != nil {
+			conn.Close()
+			return
+		}
+		if readString() != username || readString() != password {
+			conn.Write([]byte{0x01, 0x01})
+			conn.Close()
+			return
+		}
+		conn.Write([]byte{0x01, 0x00})
+
+		// Connect request, only for IPv4 addresses.
+		if _, err := io.ReadFull(r, buf[:4]); err != nil || buf[3] != 0x01 {
+			conn.Close()
+			return
+		}
+		if _, err := io.ReadFull(r, buf[:6]); err != nil {
+			conn.Close()
+			return
+		}
+		ip := net.IP(append([]byte{}, buf[:4]...))
+		port := binary.BigEndian.Uint16(buf[4:6])
+		conn.Write([]byte{0x05, 0x00, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+
+		testServeTunnel(&bufferedConn{Conn: conn, r: r}, net.JoinHostPort(ip.String(), strconv.Itoa(int(port))))
+	}
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn)
+		}
+	}()
+
+	return l.Addr().(*net.TCPAddr)
+}
+
+// newTestHttpProxy starts an HTTP proxy only accepting the CONNECT method with
+// the basic authentication, and returns its address.
+func newTestHttpProxy(t *testing.T, username, password string) *net.TCPAddr {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	cred := "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+	go http.Serve(l, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if r.Header.Get("Proxy-Authorization") != cred {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+
+		conn, brw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		testServeTunnel(&bufferedConn{Conn: conn, r: brw.Reader}, r.Host)
+	}))
+
+	return l.Addr().(*net.TCPAddr)
+}
+
+func TestHostRunCommandWithProxy(t *testing.T) {
+	os.Setenv("SSHCLIENT_TEST_PROXY_PASSWORD", "proxypass")
+	defer os.Unsetenv("SSHCLIENT_TEST_PROXY_PASSWORD")
+
+	s := newTestSSHServer(t, &ssh.ServerConfig{NoClientAuth: true})
+	socks5 := newTestSocks5Proxy(t, "proxyuser", "proxypass")
+	httpProxy := newTestHttpProxy(t, "proxyuser", "proxypass")
+
+	cases := []struct {
+		name     string
+		proxy    hostProxy
+		accepted bool
+	}{
+		{
+			name:     "socks5",
+			proxy:    hostProxy{Type: proxyTypeSocks5, Hostname: "127.0.0.1", Port: socks5.Port, Username: "proxyuser", Password: "proxypass"},
+			accepted: true,
+		},
+		{
+			name:  "socks5 with wrong password",
+			proxy: hostProxy{Type: proxyTypeSocks5, Hostname: "127.0.0.1", Port: socks5.Port, Username: "proxyuser", Password: "wrong"},
+		},
+		{
+			name:     "http",
+			proxy:    hostProxy{Type: proxyTypeHttp, Hostname: "127.0.0.1", Port: httpProxy.Port, Username: "proxyuser", Password: "proxypass"},
+			accepted: true,
+		},
+		{
+			name:     "socks5 with password_from",
+			proxy:    hostProxy{Type: proxyTypeSocks5, Hostname: "127.0.0.1", Port: socks5.Port, Username: "proxyuser", PasswordFrom: &secretSource{Env: "SSHCLIENT_TEST_PROXY_PASSWORD"}},
+			accepted: true,
+		},
+		{
+			name:     "http with password_from",
+			proxy:    hostProxy{Type: proxyTypeHttp, Hostname: "127.0.0.1", Port: httpProxy.Port, Username: "proxyuser", PasswordFrom: &secretSource{Env: "SSHCLIENT_TEST_PROXY_PASSWORD"}},
+			accepted: true,
+		},
+		{
+			name:  "http with wrong password",
+			proxy: hostProxy{Type: proxyTypeHttp, Hostname: "127.0.0.1", Port: httpProxy.Port, Username: "proxyuser", Password: "wrong"},
+		},
+	}
+	for _, c := range cases {
+		h := s.host("user")
+		h.Password = "unused"
+		h.Proxy = &c.proxy
+
+		if err := h.validateHostInfo(); err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+
+		var stdout, stderr bytes.Buffer
+		err := h.RunCommand("echo hi", &stdout, &stderr)
+		if (err == nil) != c.accepted {
+			t.Errorf(`%s: error status not match:
+	Error:                %v
+	Expected to succeed?: %v`, c.name, err, c.accepted)
+			continue
+		}
+		if err == nil && stdout.String() != "echo hi" {
+			t.Errorf("%s: unexpected stdout: %q", c.name, stdout.String())
+		}
+	}
+}
+
+func TestHostProxyValidate(t *testing.T) {
+	cases := []struct {
+		proxy    hostProxy
+		accepted bool
+	}{
+		{
+			proxy:    hostProxy{Type: proxyTypeSocks5, Hostname: "proxy", Port: 1080},
+			accepted: true,
+		},
+		{
+			proxy: hostProxy{Type: "ftp", Hostname: "proxy", Port: 1080},
+		},
+		{
+			proxy: hostProxy{Type: proxyTypeHttp, Port: 3128},
+		},
+		{
+			proxy: hostProxy{Type: proxyTypeHttp, Hostname: "proxy", Port: 0},
+		},
+		{
+			proxy: hostProxy{Type: proxyTypeHttp, Hostname: "proxy", Port: 3128, Password: "pw"},
+		},
+		{
+			proxy: hostProxy{Type: proxyTypeHttp, Hostname: "proxy", Port: 3128, PasswordFrom: &secretSource{Env: "PW"}},
+		},
+	}
+	for _, c := range cases {
+		err := c.proxy.validate()
+		if (err == nil) != c.accepted {
+			t.Errorf(`Error status not match:
+	Case:                 %#v
+	Succeeded?:           %v
+	Expected to succeed?: %v`, c.proxy, err == nil, c.accepted)
+		}
+	}
+}
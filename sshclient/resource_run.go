@@ -55,6 +55,16 @@ func resourceRun() *schema.Resource {
 				Type:     schema.TypeString,
 				Computed: true,
 			},
+			"matched_host_key": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Host key accepted on the last connection in authorized_keys (sshd(8)) format.",
+			},
+			"authenticated_method": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Auth method that completed authentication on the last connection, such as password or private_key.",
+			},
 			"destroy_command": {
 				Type:        schema.TypeString,
 				Optional:    true,
@@ -86,11 +96,7 @@ func resourceRunCommon(
 	cmd, cmd64, exp, keyOut, keyOut64, keyErr, keyErr64 string,
 	timeout time.Duration,
 ) error {
-	if err := h.validateHostInfo(); err != nil {
-		return err
-	}
-
-	if err := h.validateAuthInfo(); err != nil {
+	if err := h.validate(true); err != nil {
 		return err
 	}
 
@@ -166,6 +172,8 @@ stderr:
 		}
 	}
 
+	d.Set("matched_host_key", h.MatchedHostKey())
+	d.Set("authenticated_method", h.AuthenticatedMethod())
 	if keyOut != "" {
 		d.Set(keyOut, stdout.String())
 	}
@@ -185,7 +193,7 @@ stderr:
 func resourceRunCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
 	j := d.Get("host_json").(string)
 
-	h, err := UnmarshalHost(j)
+	h, err := unmarshalHostWithDefaults(j, m)
 	if err != nil {
 		return diag.FromErr(err)
 	}
@@ -217,17 +225,13 @@ func resourceRunCreate(ctx context.Context, d *schema.ResourceData, m interface{
 }
 
 func resourceRunRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	h, err := UnmarshalHost(d.Get("host_json").(string))
+	h, err := unmarshalHostWithDefaults(d.Get("host_json").(string), m)
 
 	if err != nil {
 		return diag.FromErr(err)
 	}
 
-	if err := h.validateHostInfo(); err != nil {
-		return diag.FromErr(err)
-	}
-
-	if err := h.validateAuthInfo(); err != nil {
+	if err := h.validate(true); err != nil {
 		return diag.FromErr(err)
 	}
 
@@ -238,7 +242,7 @@ func resourceRunRead(ctx context.Context, d *schema.ResourceData, m interface{})
 func resourceRunUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
 	j := d.Get("host_json").(string)
 
-	h, err := UnmarshalHost(j)
+	h, err := unmarshalHostWithDefaults(j, m)
 	if err != nil {
 		return diag.FromErr(err)
 	}
@@ -269,7 +273,7 @@ func resourceRunUpdate(ctx context.Context, d *schema.ResourceData, m interface{
 func resourceRunDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
 	j := d.Get("host_json").(string)
 
-	h, err := UnmarshalHost(j)
+	h, err := unmarshalHostWithDefaults(j, m)
 	if err != nil {
 		return diag.FromErr(err)
 	}
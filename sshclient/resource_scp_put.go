@@ -49,6 +49,16 @@ func resourceScpPut() *schema.Resource {
 				Required:    true,
 				Description: "Remote path to place the file. Take care to avoid including spaces or other special characters. These may be troublesome when being interpreted by remote shell.",
 			},
+			"matched_host_key": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Host key accepted on the last connection in authorized_keys (sshd(8)) format.",
+			},
+			"authenticated_method": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Auth method that completed authentication on the last connection, such as password or private_key.",
+			},
 			"permissions": {
 				Type:     schema.TypeString,
 				Default:  permDef,
@@ -93,29 +103,26 @@ func resourceScpPutCreateUpdate(ctx context.Context, d *schema.ResourceData, m i
 
 	j := d.Get("host_json").(string)
 
-	h, err := UnmarshalHost(j)
+	h, err := unmarshalHostWithDefaults(j, m)
 	if err != nil {
 		return diag.FromErr(err)
 	}
 
 	err = func() error {
-		if err := h.validateHostInfo(); err != nil {
+		if err := h.validate(true); err != nil {
 			return err
 		}
 
-		if err := h.validateAuthInfo(); err != nil {
+		conn, release, err := h.pool.acquire(h)
+		if err != nil {
 			return err
 		}
+		defer release()
 
-		client, err := h.ClientConfig()
+		c, err := scp.NewClientBySSHWithTimeout(conn, timeout)
 		if err != nil {
 			return err
 		}
-
-		c := scp.NewClientWithTimeout(fmt.Sprintf("%s:%d", h.Hostname, h.Port), client, timeout)
-		if err = c.Connect(); err != nil {
-			return nil
-		}
 		defer c.Close()
 
 		remotePath := d.Get("remote_path").(string)
@@ -129,11 +136,18 @@ func resourceScpPutCreateUpdate(ctx context.Context, d *schema.ResourceData, m i
 			return err
 		}
 
+		stopKeepalive := h.keepalive(conn)
 		err = c.CopyFile(bytes.NewReader(b), remotePath, perm)
+		if lost := stopKeepalive(); lost != nil {
+			return lost
+		}
 		if err != nil {
 			return err
 		}
 
+		d.Set("matched_host_key", h.MatchedHostKey())
+		d.Set("authenticated_method", h.AuthenticatedMethod())
+
 		return nil
 	}()
 
@@ -158,17 +172,13 @@ func resourceScpPutCreate(ctx context.Context, d *schema.ResourceData, m interfa
 }
 
 func resourceScpPutRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	h, err := UnmarshalHost(d.Get("host_json").(string))
+	h, err := unmarshalHostWithDefaults(d.Get("host_json").(string), m)
 
 	if err != nil {
 		return diag.FromErr(err)
 	}
 
-	if err := h.validateHostInfo(); err != nil {
-		return diag.FromErr(err)
-	}
-
-	if err := h.validateAuthInfo(); err != nil {
+	if err := h.validate(true); err != nil {
 		return diag.FromErr(err)
 	}
 
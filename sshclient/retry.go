@@ -0,0 +1,232 @@
+package sshclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"math/rand"
+	"net"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	retryConnectionRefused = "connection_refused"
+	retryTimeout           = "timeout"
+	retryUnreachable       = "unreachable"
+	retryReset             = "reset"
+	retryHostKey           = "host_key"
+	retryAuth              = "auth"
+
+	retryDefaultInitialBackoff = time.Second
+	retryDefaultMaxBackoff     = 30 * time.Second
+)
+
+var (
+	retryErrorKinds = []string{
+		retryConnectionRefused,
+		retryTimeout,
+		retryUnreachable,
+		retryReset,
+		retryHostKey,
+		retryAuth,
+	}
+	retryDefaultErrors = []string{
+		retryConnectionRefused,
+		retryTimeout,
+		retryUnreachable,
+		retryReset,
+	}
+)
+
+type hostRetry struct {
+	MaxAttempts     int      `json:"max_attempts"`
+	InitialBackoff  string   `json:"initial_backoff"`
+	MaxBackoff      string   `json:"max_backoff"`
+	Jitter          float64  `json:"jitter"`
+	Timeout         string   `json:"timeout"`
+	RetryableErrors []string `json:"retryable_errors"`
+}
+
+func parseOptionalDuration(name, s string, def time.Duration) (time.Duration, error) {
+	if s == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return d, nil
+}
+
+func (r *hostRetry) validate() error {
+	if r.MaxAttempts < 0 {
+		return fmt.Errorf("retry max_attempts must not be negative")
+	}
+	if r.Jitter < 0 || r.Jitter > 1 {
+		return fmt.Errorf("retry jitter must be between 0 and 1")
+	}
+	if _, err := parseOptionalDuration("retry initial_backoff", r.InitialBackoff, 0); err != nil {
+		return err
+	}
+	if _, err := parseOptionalDuration("retry max_backoff", r.MaxBackoff, 0); err != nil {
+		return err
+	}
+	if _, err := parseOptionalDuration("retry timeout", r.Timeout, 0); err != nil {
+		return err
+	}
+	for _, e := range r.RetryableErrors {
+		if !stringInSlice(e, retryErrorKinds) {
+			return fmt.Errorf("unknown retryable error %q, must be one of %s", e, strings.Join(retryErrorKinds, ", "))
+		}
+	}
+	return nil
+}
+
+func stringInSlice(s string, list []string) bool {
+	for _, l := range list {
+		if s == l {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the wait before the attempt following the given one.
+func (r *hostRetry) backoff(attempt int) time.Duration {
+	initial, _ := parseOptionalDuration("", r.InitialBackoff, retryDefaultInitialBackoff)
+	max, _ := parseOptionalDuration("", r.MaxBackoff, retryDefaultMaxBackoff)
+
+	b := float64(initial) * math.Pow(2, float64(attempt-1))
+	if b > float64(max) {
+		b = float64(max)
+	}
+	b *= 1 - r.Jitter*retryJitter()
+	return time.Duration(b)
+}
+
+// retryRand is seeded per process so that processes retrying the same host do
+// not back off in lockstep. It is guarded by retryRandMu because rand.Rand is
+// not safe for concurrent use.
+var (
+	retryRandMu sync.Mutex
+	retryRand   = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// retryJitter returns a random number in [0.0, 1.0).
+func retryJitter() float64 {
+	retryRandMu.Lock()
+	defer retryRandMu.Unlock()
+	return retryRand.Float64()
+}
+
+func (r *hostRetry) retryable(err error) bool {
+	kinds := r.RetryableErrors
+	if len(kinds) == 0 {
+		kinds = retryDefaultErrors
+	}
+	return stringInSlice(classifyDialError(err), kinds)
+}
+
+// classifyDialError returns the kind of err in retryErrorKinds, or an empty
+// string if unknown. Handshake errors are classified by their messages because
+// x/crypto/ssh does not wrap them.
+func classifyDialError(err error) string {
+	var netErr net.Error
+	msg := err.Error()
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED) || strings.Contains(msg, "connection refused"):
+		return retryConnectionRefused
+	case errors.As(err, &netErr) && netErr.Timeout() || strings.Contains(msg, "i/o timeout"):
+		return retryTimeout
+	case errors.Is(err, syscall.EHOSTUNREACH) || errors.Is(err, syscall.ENETUNREACH) ||
+		strings.Contains(msg, "no route to host") || strings.Contains(msg, "network is unreachable"):
+		return retryUnreachable
+	case errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.EOF) ||
+		strings.Contains(msg, "connection reset") || strings.HasSuffix(msg, "EOF"):
+		return retryReset
+	case strings.Contains(msg, "ssh: unable to authenticate"):
+		return retryAuth
+	case strings.Contains(msg, "ssh: handshake failed") && isHostKeyError(msg):
+		return retryHostKey
+	}
+	return ""
+}
+
+// hostKeyErrorMessages are the messages of host key callback failures that
+// may be resolved once the host finishes booting, such as a key not generated
+// yet. Revoked keys are not included.
+var hostKeyErrorMessages = []string{
+	// knownhosts.KeyError
+	"knownhosts: key is unknown",
+	"knownhosts: key mismatch",
+	// pinnedHostKeysCallback and anyHostKeyCallback
+	"is not pinned",
+	"host key is not trusted",
+	// hostCertCallback and ssh.CertChecker
+	"no principal accepted",
+	"ssh: non-certificate host key",
+	"ssh: no authorities for hostname",
+	"ssh: certificate signed by unrecognized authority",
+	"not in the set of valid principals",
+	"ssh: cert is not yet valid",
+	"ssh: cert has expired",
+}
+
+func isHostKeyError(msg string) bool {
+	for _, m := range hostKeyErrorMessages {
+		if strings.Contains(msg, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// withRetry calls f until it succeeds, it fails with a non-retryable error or
+// the retry policy is exhausted.
+func (h *host) withRetry(f func() error) error {
+	return h.withRetryContext(context.Background(), f)
+}
+
+// withRetryContext is withRetry that stops waiting for the next attempt when
+// ctx is done.
+func (h *host) withRetryContext(ctx context.Context, f func() error) error {
+	r := h.Retry
+	if r == nil || r.MaxAttempts <= 1 {
+		return f()
+	}
+
+	timeout, _ := parseOptionalDuration("", r.Timeout, 0)
+	start := time.Now()
+
+	for attempt := 1; ; attempt++ {
+		err := f()
+		if err == nil {
+			return nil
+		}
+
+		if attempt >= r.MaxAttempts {
+			return fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		}
+		if !r.retryable(err) {
+			return err
+		}
+
+		wait := r.backoff(attempt)
+		if timeout > 0 && time.Since(start)+wait > timeout {
+			return fmt.Errorf("giving up after %d attempts in retry timeout %s: %w", attempt, timeout, err)
+		}
+
+		log.Printf("[INFO] %s: connection attempt %d failed (%s): %s; retrying in %s", h, attempt, classifyDialError(err), err, wait)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return fmt.Errorf("giving up after %d attempts: %s: %w", attempt, ctx.Err(), err)
+		}
+	}
+}
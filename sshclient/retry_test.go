@@ -0,0 +1,106 @@
+package sshclient
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestClassifyDialError(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		kind string
+	}{
+		{"refused", &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, retryConnectionRefused},
+		{"unreachable", fmt.Errorf("dial: %w", syscall.EHOSTUNREACH), retryUnreachable},
+		{"reset", fmt.Errorf("read: %w", syscall.ECONNRESET), retryReset},
+		{"handshake EOF", errors.New("ssh: handshake failed: EOF"), retryReset},
+		{"auth", errors.New("ssh: handshake failed: ssh: unable to authenticate, attempted methods [none password], no supported methods remain"), retryAuth},
+		{"host key", errors.New("ssh: handshake failed: knownhosts: key is unknown"), retryHostKey},
+		{"pinned host key", errors.New("ssh: handshake failed: host key ssh-ed25519 SHA256:abc is not pinned"), retryHostKey},
+		{"host certificate", errors.New("ssh: handshake failed: no principal accepted: ssh: cert has expired"), retryHostKey},
+		{"revoked host key", errors.New("ssh: handshake failed: host key is revoked"), ""},
+		{"no common algorithm", errors.New("ssh: handshake failed: ssh: no common algorithm for host key; client offered: [ssh-ed25519], server offered: [ssh-rsa]"), ""},
+		{"other", errors.New("something else"), ""},
+	}
+
+	for _, c := range cases {
+		if kind := classifyDialError(c.err); kind != c.kind {
+			t.Errorf("Kind not match:\n\tCase: %s\n\tKind: %q\n\tExpected: %q", c.name, kind, c.kind)
+		}
+	}
+}
+
+func TestHostWithRetry(t *testing.T) {
+	refused := &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}
+	auth := errors.New("ssh: handshake failed: ssh: unable to authenticate")
+
+	cases := []struct {
+		name     string
+		retry    *hostRetry
+		errs     []error
+		attempts int
+		success  bool
+	}{
+		{"no retry", nil, []error{refused, nil}, 1, false},
+		{"refused then success", &hostRetry{MaxAttempts: 3, InitialBackoff: "1ms"}, []error{refused, refused, nil}, 3, true},
+		{"attempts exhausted", &hostRetry{MaxAttempts: 2, InitialBackoff: "1ms"}, []error{refused, refused, nil}, 2, false},
+		{"not retryable", &hostRetry{MaxAttempts: 3, InitialBackoff: "1ms"}, []error{auth, nil}, 1, false},
+		{"retryable auth", &hostRetry{MaxAttempts: 3, InitialBackoff: "1ms", RetryableErrors: []string{"auth"}}, []error{auth, nil}, 2, true},
+		{"timeout", &hostRetry{MaxAttempts: 3, InitialBackoff: "1h", Timeout: "1s"}, []error{refused, nil}, 1, false},
+	}
+
+	for _, c := range cases {
+		h := &host{Hostname: "localhost", Port: 22, Retry: c.retry}
+		attempts := 0
+		err := h.withRetry(func() error {
+			err := c.errs[attempts]
+			attempts++
+			return err
+		})
+
+		if succeeded := err == nil; succeeded != c.success {
+			t.Errorf("Error status not match:\n\tCase: %s\n\tSucceeded?: %v\n\tExpected to succeed?: %v\n\tError: %v", c.name, succeeded, c.success, err)
+		}
+		if attempts != c.attempts {
+			t.Errorf("Attempts not match:\n\tCase: %s\n\tAttempts: %d\n\tExpected: %d", c.name, attempts, c.attempts)
+		}
+	}
+}
+
+func TestHostRetryBackoff(t *testing.T) {
+	r := &hostRetry{InitialBackoff: "1s", MaxBackoff: "5s", Jitter: 0.5}
+
+	for attempt, max := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second} {
+		b := r.backoff(attempt + 1)
+		if b < max/2 || b > max {
+			t.Errorf("Backoff out of range:\n\tAttempt: %d\n\tBackoff: %s\n\tExpected: %s to %s", attempt+1, b, max/2, max)
+		}
+	}
+}
+
+func TestHostRetryValidate(t *testing.T) {
+	cases := []struct {
+		name    string
+		retry   hostRetry
+		success bool
+	}{
+		{"minimal", hostRetry{MaxAttempts: 3}, true},
+		{"full", hostRetry{MaxAttempts: 3, InitialBackoff: "2s", MaxBackoff: "1m", Jitter: 0.2, Timeout: "5m", RetryableErrors: []string{"connection_refused", "host_key"}}, true},
+		{"negative attempts", hostRetry{MaxAttempts: -1}, false},
+		{"jitter", hostRetry{MaxAttempts: 3, Jitter: 1.5}, false},
+		{"invalid backoff", hostRetry{MaxAttempts: 3, InitialBackoff: "soon"}, false},
+		{"unknown error", hostRetry{MaxAttempts: 3, RetryableErrors: []string{"everything"}}, false},
+	}
+
+	for _, c := range cases {
+		err := c.retry.validate()
+		if succeeded := err == nil; succeeded != c.success {
+			t.Errorf("Error status not match:\n\tCase: %s\n\tSucceeded?: %v\n\tExpected to succeed?: %v\n\tError: %v", c.name, succeeded, c.success, err)
+		}
+	}
+}
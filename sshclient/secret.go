@@ -0,0 +1,317 @@
+package sshclient
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// secretSource refers to a secret resolved when connecting, so that the
+// secret itself is not stored in host_json nor in the Terraform state.
+type secretSource struct {
+	Env     string `json:"env"`
+	File    string `json:"file"`
+	Command string `json:"command"`
+}
+
+// secretSourceSchema returns the schema of a block referring to the secret of
+// attribute name.
+func secretSourceSchema(name string) *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeList,
+		Optional:    true,
+		MaxItems:    1,
+		Description: fmt.Sprintf("Source of %s read when connecting, so that it is not stored in host_json nor in the state. Exactly one of env, file and command is needed.", name),
+		Elem: &schema.Resource{
+			Schema: secretSourceAttributes(),
+		},
+	}
+}
+
+// secretSourceAttributes returns the attributes of a block referring to a
+// secret.
+func secretSourceAttributes() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"env": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			Description: "Name of the environment variable holding the secret.",
+		},
+		"file": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			Description: "Path to the file holding the secret.",
+		},
+		"command": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			Description: "Command run with the shell that prints the secret to stdout, such as a credential helper.",
+		},
+	}
+}
+
+// keyboardInteractiveAnswerSourceAttributes returns the attributes of a block
+// of keyboard_interactive_answers_from.
+func keyboardInteractiveAnswerSourceAttributes() map[string]*schema.Schema {
+	attrs := secretSourceAttributes()
+	attrs["pattern"] = &schema.Schema{
+		Type:        schema.TypeString,
+		Required:    true,
+		Description: "Regular expression matched against keyboard-interactive prompts.",
+	}
+	return attrs
+}
+
+func newSecretSource(s map[string]interface{}) *secretSource {
+	return &secretSource{
+		Env:     s["env"].(string),
+		File:    s["file"].(string),
+		Command: s["command"].(string),
+	}
+}
+
+// attributes returns s in the form of secretSourceAttributes.
+func (s *secretSource) attributes() map[string]interface{} {
+	return map[string]interface{}{
+		"env":     s.Env,
+		"file":    s.File,
+		"command": s.Command,
+	}
+}
+
+// readSecretSource merges the block of d named name+"_from" into f like the
+// other attributes of sshclient_host. Setting either the secret or its source
+// overrides both of them in extends_host_json.
+func readSecretSource(d *schema.ResourceData, f secretField) {
+	if ss, ok := d.GetOk(f.sourceName); ok {
+		*f.source = newSecretSource(ss.([]interface{})[0].(map[string]interface{}))
+		*f.value = ""
+		d.Set(f.name, "")
+	} else if _, ok := d.GetOk(f.name); ok {
+		*f.source = nil
+	} else if s := *f.source; s != nil {
+		d.Set(f.sourceName, []interface{}{s.attributes()})
+	}
+}
+
+func (s *secretSource) validate(name string) error {
+	n := 0
+	for _, set := range []bool{s.Env != "", s.File != "", s.Command != ""} {
+		if set {
+			n++
+		}
+	}
+	if n != 1 {
+		return fmt.Errorf("exactly one of env, file and command is needed in %s", name)
+	}
+	return nil
+}
+
+// resolve returns the secret without trailing newlines.
+func (s *secretSource) resolve(name string) (string, error) {
+	var value string
+	switch {
+	case s.Env != "":
+		v, ok := os.LookupEnv(s.Env)
+		if !ok {
+			return "", fmt.Errorf("failed to resolve %s: environment variable %s is not set", name, s.Env)
+		}
+		value = v
+	case s.File != "":
+		b, err := os.ReadFile(s.File)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve %s: %w", name, err)
+		}
+		value = string(b)
+	case s.Command != "":
+		var stdout, stderr bytes.Buffer
+		cmd := shellCommand(s.Command)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return "", fmt.Errorf("failed to resolve %s: command failed: %w: %s", name, err, strings.TrimSpace(stderr.String()))
+		}
+		value = stdout.String()
+	}
+
+	value = strings.TrimRight(value, "\r\n")
+	if value == "" {
+		return "", fmt.Errorf("failed to resolve %s: secret is empty", name)
+	}
+	return value, nil
+}
+
+// readKeyboardInteractiveAnswers merges keyboard_interactive_answers and
+// keyboard_interactive_answers_from of d into h like readSecretSource.
+func readKeyboardInteractiveAnswers(d *schema.ResourceData, h *host) {
+	if sources, ok := d.GetOk("keyboard_interactive_answers_from"); ok {
+		h.KeyboardInteractiveAnswersFrom = map[string]*secretSource{}
+		for _, s := range sources.([]interface{}) {
+			s := s.(map[string]interface{})
+			h.KeyboardInteractiveAnswersFrom[s["pattern"].(string)] = newSecretSource(s)
+		}
+		h.KeyboardInteractiveAnswers = nil
+		d.Set("keyboard_interactive_answers", nil)
+	} else if answers, ok := d.GetOk("keyboard_interactive_answers"); ok {
+		h.KeyboardInteractiveAnswers = map[string]string{}
+		for p, a := range answers.(map[string]interface{}) {
+			h.KeyboardInteractiveAnswers[p] = a.(string)
+		}
+		h.KeyboardInteractiveAnswersFrom = nil
+	} else {
+		d.Set("keyboard_interactive_answers", h.KeyboardInteractiveAnswers)
+		patterns := make([]string, 0, len(h.KeyboardInteractiveAnswersFrom))
+		for p := range h.KeyboardInteractiveAnswersFrom {
+			patterns = append(patterns, p)
+		}
+		sort.Strings(patterns)
+		sources := make([]interface{}, 0, len(patterns))
+		for _, p := range patterns {
+			s := h.KeyboardInteractiveAnswersFrom[p].attributes()
+			s["pattern"] = p
+			sources = append(sources, s)
+		}
+		d.Set("keyboard_interactive_answers_from", sources)
+	}
+}
+
+func (h *host) hasPassword() bool {
+	return h.Password != "" || h.PasswordFrom != nil
+}
+
+func (h *host) hasPrivateKey() bool {
+	return h.ClientPrivateKeyPem != "" || h.ClientPrivateKeyPemFrom != nil
+}
+
+// secretField is a secret field of host with its source.
+type secretField struct {
+	name       string
+	sourceName string
+	value      *string
+	source     **secretSource
+}
+
+// attributeSecretFields returns the secret fields of h having attributes of
+// sshclient_host named name and sourceName.
+func (h *host) attributeSecretFields() []secretField {
+	return []secretField{
+		{"password", "password_from", &h.Password, &h.PasswordFrom},
+		{"client_private_key_pem", "client_private_key_pem_from", &h.ClientPrivateKeyPem, &h.ClientPrivateKeyPemFrom},
+		{"client_private_key_passphrase", "client_private_key_passphrase_from", &h.ClientPrivateKeyPassphrase, &h.ClientPrivateKeyPassphraseFrom},
+	}
+}
+
+// proxySecretFields returns the secret fields of the proxy of h, which are
+// needed to connect even without authenticating, like sshclient_keyscan.
+func (h *host) proxySecretFields() []secretField {
+	if h.Proxy == nil {
+		return nil
+	}
+	return []secretField{
+		{"proxy.password", "proxy.password_from", &h.Proxy.Password, &h.Proxy.PasswordFrom},
+	}
+}
+
+// authSecretFields returns the secret fields of h used to authenticate.
+func (h *host) authSecretFields() []secretField {
+	fields := h.attributeSecretFields()
+	patterns := make([]string, 0, len(h.KeyboardInteractiveAnswersFrom))
+	for p := range h.KeyboardInteractiveAnswersFrom {
+		patterns = append(patterns, p)
+	}
+	sort.Strings(patterns)
+	for _, p := range patterns {
+		// The answer is copied since map elements are not addressable. It is
+		// only read to reject a pattern having both an answer and a source.
+		answer := h.KeyboardInteractiveAnswers[p]
+		source := h.KeyboardInteractiveAnswersFrom[p]
+		fields = append(fields, secretField{
+			fmt.Sprintf("keyboard_interactive_answers[%q]", p),
+			fmt.Sprintf("keyboard_interactive_answers_from[%q]", p),
+			&answer,
+			&source,
+		})
+	}
+	return fields
+}
+
+func (h *host) secretFields() []secretField {
+	return append(h.proxySecretFields(), h.authSecretFields()...)
+}
+
+func validateSecretSources(fields []secretField) error {
+	for _, s := range fields {
+		if *s.source == nil {
+			continue
+		}
+		if *s.value != "" {
+			return fmt.Errorf("%s and %s cannot be used together", s.name, s.sourceName)
+		}
+		if err := (*s.source).validate(s.sourceName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveSecrets resolves fields of h from their sources. The values are kept
+// apart from the fields so that h stays valid.
+func (h *host) resolveSecrets(fields []secretField) error {
+	resolved := map[string]string{}
+	for _, s := range fields {
+		if *s.source == nil {
+			continue
+		}
+		v, err := (*s.source).resolve(s.name)
+		if err != nil {
+			return err
+		}
+		resolved[s.name] = v
+	}
+	h.resolvedSecrets = resolved
+	return nil
+}
+
+// secret returns the value of the secret field name, which is resolved from
+// its source if any.
+func (h *host) secret(name string) string {
+	for _, s := range h.secretFields() {
+		if s.name != name {
+			continue
+		}
+		if *s.source != nil {
+			return h.resolvedSecrets[name]
+		}
+		return *s.value
+	}
+	return ""
+}
+
+func (h *host) password() string {
+	return h.secret("password")
+}
+
+func (h *host) clientPrivateKeyPem() string {
+	return h.secret("client_private_key_pem")
+}
+
+func (h *host) clientPrivateKeyPassphrase() string {
+	return h.secret("client_private_key_passphrase")
+}
+
+func (h *host) proxyPassword() string {
+	return h.secret("proxy.password")
+}
+
+// keyboardInteractiveAnswer returns the answer for pattern of
+// keyboard_interactive_answers or keyboard_interactive_answers_from.
+func (h *host) keyboardInteractiveAnswer(pattern string) string {
+	if _, ok := h.KeyboardInteractiveAnswersFrom[pattern]; ok {
+		return h.resolvedSecrets[fmt.Sprintf("keyboard_interactive_answers[%q]", pattern)]
+	}
+	return h.KeyboardInteractiveAnswers[pattern]
+}
@@ -0,0 +1,171 @@
+package sshclient
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestSecretSourceResolve(t *testing.T) {
+	os.Setenv("SSHCLIENT_TEST_SECRET", "from env")
+	defer os.Unsetenv("SSHCLIENT_TEST_SECRET")
+
+	file := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(file, []byte("from file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name   string
+		source secretSource
+		value  string
+	}{
+		{"env", secretSource{Env: "SSHCLIENT_TEST_SECRET"}, "from env"},
+		{"unset env", secretSource{Env: "SSHCLIENT_TEST_UNSET"}, ""},
+		{"file", secretSource{File: file}, "from file"},
+		{"missing file", secretSource{File: filepath.Join(t.TempDir(), "missing")}, ""},
+		{"command", secretSource{Command: "echo from command"}, "from command"},
+		{"failing command", secretSource{Command: "echo oops >&2; exit 1"}, ""},
+		{"empty command output", secretSource{Command: "true"}, ""},
+	}
+
+	for _, c := range cases {
+		value, err := c.source.resolve("password")
+		if succeeded := err == nil; succeeded != (c.value != "") {
+			t.Errorf("Error status not match:\n\tCase: %s\n\tSucceeded?: %v\n\tExpected to succeed?: %v\n\tError: %v", c.name, succeeded, c.value != "", err)
+		}
+		if value != c.value {
+			t.Errorf("Value not match:\n\tCase: %s\n\tValue: %q\n\tExpected: %q", c.name, value, c.value)
+		}
+	}
+}
+
+func TestHostValidateSecretSources(t *testing.T) {
+	cases := []struct {
+		name    string
+		host    host
+		success bool
+	}{
+		{"password_from", host{PasswordFrom: &secretSource{Env: "PW"}}, true},
+		{"client_private_key_pem_from", host{ClientPrivateKeyPemFrom: &secretSource{File: "key"}, ClientPrivateKeyPassphraseFrom: &secretSource{Command: "pass show key"}}, true},
+		{"both", host{Password: "pw", PasswordFrom: &secretSource{Env: "PW"}}, false},
+		{"no source", host{PasswordFrom: &secretSource{}}, false},
+		{"multiple sources", host{PasswordFrom: &secretSource{Env: "PW", File: "pw"}}, false},
+		{"certificate", host{ClientPrivateKeyPemFrom: &secretSource{File: "key"}, ClientCertificate: "cert"}, true},
+		{"keyboard_interactive_answers_from", host{KeyboardInteractive: true, KeyboardInteractiveAnswersFrom: map[string]*secretSource{"code": {Env: "CODE"}}}, true},
+		{"both answers", host{KeyboardInteractive: true, KeyboardInteractiveAnswers: map[string]string{"code": "123"}, KeyboardInteractiveAnswersFrom: map[string]*secretSource{"code": {Env: "CODE"}}}, false},
+		{"other answers", host{KeyboardInteractive: true, KeyboardInteractiveAnswers: map[string]string{"password": "pw"}, KeyboardInteractiveAnswersFrom: map[string]*secretSource{"code": {Env: "CODE"}}}, true},
+		{"no answer source", host{KeyboardInteractive: true, KeyboardInteractiveAnswersFrom: map[string]*secretSource{"code": {}}}, false},
+	}
+
+	for _, c := range cases {
+		err := c.host.validateAuthInfo()
+		if succeeded := err == nil; succeeded != c.success {
+			t.Errorf("Error status not match:\n\tCase: %s\n\tSucceeded?: %v\n\tExpected to succeed?: %v\n\tError: %v", c.name, succeeded, c.success, err)
+		}
+	}
+}
+
+func TestHostRunCommandWithSecretSource(t *testing.T) {
+	const password = "secret password"
+	os.Setenv("SSHCLIENT_TEST_PASSWORD", password)
+	defer os.Unsetenv("SSHCLIENT_TEST_PASSWORD")
+
+	s := newTestSSHServer(t, &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, p []byte) (*ssh.Permissions, error) {
+			if string(p) != password {
+				return nil, fmt.Errorf("wrong password")
+			}
+			return nil, nil
+		},
+	})
+
+	h := s.host("user")
+	h.PasswordFrom = &secretSource{Env: "SSHCLIENT_TEST_PASSWORD"}
+
+	j, err := MarshalHost(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(j, password) {
+		t.Errorf("host_json contains the secret: %s", j)
+	}
+
+	h, err = UnmarshalHost(j)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The host is reused after connecting, such as when reverting a failed
+	// destroy command.
+	for i := 0; i < 2; i++ {
+		if err := h.validate(true); err != nil {
+			t.Fatalf("validate after %d runs: %v", i, err)
+		}
+
+		var stdout, stderr bytes.Buffer
+		if err := h.RunCommand("echo hi", &stdout, &stderr); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	j, err = MarshalHost(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(j, password) {
+		t.Errorf("host_json contains the secret after connecting: %s", j)
+	}
+}
+
+func TestHostRunCommandWithKeyboardInteractiveAnswersFrom(t *testing.T) {
+	const code = "123456"
+	os.Setenv("SSHCLIENT_TEST_CODE", code)
+	defer os.Unsetenv("SSHCLIENT_TEST_CODE")
+
+	s := newTestSSHServer(t, &ssh.ServerConfig{
+		KeyboardInteractiveCallback: func(conn ssh.ConnMetadata, client ssh.KeyboardInteractiveChallenge) (*ssh.Permissions, error) {
+			answers, err := client("", "", []string{"Password: ", "Verification code: "}, []bool{false, true})
+			if err != nil {
+				return nil, err
+			}
+			if len(answers) != 2 || answers[0] != "secret" || answers[1] != code {
+				return nil, fmt.Errorf("wrong answers")
+			}
+			return nil, nil
+		},
+	})
+
+	h := s.host("kiuser")
+	h.KeyboardInteractive = true
+	h.KeyboardInteractiveAnswers = map[string]string{"Password": "secret"}
+	h.KeyboardInteractiveAnswersFrom = map[string]*secretSource{
+		"(?i)verification code": {Env: "SSHCLIENT_TEST_CODE"},
+	}
+
+	j, err := MarshalHost(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(j, code) {
+		t.Errorf("host_json contains the secret: %s", j)
+	}
+
+	h, err = UnmarshalHost(j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.validate(true); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if err := h.RunCommand("echo hi", &stdout, &stderr); err != nil {
+		t.Fatal(err)
+	}
+}